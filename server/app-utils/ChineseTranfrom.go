@@ -1,12 +1,71 @@
 package app_utils
 
-import "github.com/liuzl/gocc"
+import (
+	"fmt"
+	"sync"
+
+	"github.com/liuzl/gocc"
+)
+
+// script 对应的 OpenCC 配置, original 表示不转换
+var scriptProfiles = map[string]string{
+	"s":  "t2s",
+	"t":  "s2t",
+	"tw": "s2tw",
+	"hk": "s2hk",
+}
+
+var (
+	convertersMu sync.Mutex
+	converters   = map[string]*gocc.OpenCC{}
+)
+
+// 加载词典较慢, 每个配置只加载一次
+func converter(profile string) (*gocc.OpenCC, error) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	if cc, ok := converters[profile]; ok {
+		return cc, nil
+	}
+	cc, err := gocc.New(profile)
+	if err != nil {
+		return nil, err
+	}
+	converters[profile] = cc
+	return cc, nil
+}
 
 // T2s 繁转简
 func T2s(string2 string) (string, error) {
-	t2s, err := gocc.New("t2s")
+	t2s, err := converter("t2s")
 	if err != nil {
 		return "", err
 	}
 	return t2s.Convert(string2)
 }
+
+// ValidScript script 为空或 original 时不转换
+func ValidScript(script string) bool {
+	_, ok := scriptProfiles[script]
+	return ok || script == "" || script == "original"
+}
+
+// ConvertScript 转换为 s / t / tw / hk, 失败时返回原文
+func ConvertScript(text string, script string) (string, error) {
+	profile, ok := scriptProfiles[script]
+	if !ok {
+		if ValidScript(script) {
+			return text, nil
+		}
+		return text, fmt.Errorf("unknown script %q", script)
+	}
+	cc, err := converter(profile)
+	if err != nil {
+		return text, err
+	}
+	converted, err := cc.Convert(text)
+	if err != nil {
+		return text, err
+	}
+	return converted, nil
+}
@@ -0,0 +1,102 @@
+package app_utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"lyrics/config"
+	"lyrics/replay"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*http.Client{}
+	// 所有客户端共用按 host 的限制, 避免不同 Provider 同时压同一个上游
+	sharedLimits = newHostLimits(config.C.HTTP)
+)
+
+// Client 返回 Provider 专用的客户端, 按 config.C.Outbound.Profile(name) 设置代理 / UA / 请求头 / CA / 连接池
+func Client(name string) *http.Client {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if client, ok := clients[name]; ok {
+		return client
+	}
+	client, err := newClient(name, config.C.Outbound.Profile(name))
+	if err != nil {
+		log.Fatalf("[ERROR] Invalid Outbound Config [%s]: %s", name, err)
+	}
+	clients[name] = client
+	return client
+}
+
+func newClient(name string, profile config.OutboundProfile) (*http.Client, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch profile.Proxy {
+	case "":
+	case "direct":
+		base.Proxy = nil
+	default:
+		proxy, err := url.Parse(profile.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+		}
+		base.Proxy = http.ProxyURL(proxy)
+	}
+
+	if profile.CAFile != "" {
+		pem, err := os.ReadFile(profile.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", profile.CAFile)
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if profile.MaxIdleConns > 0 {
+		base.MaxIdleConns = profile.MaxIdleConns
+	}
+	if profile.MaxIdleConnsPerHost > 0 {
+		base.MaxIdleConnsPerHost = profile.MaxIdleConnsPerHost
+	}
+	if profile.MaxConnsPerHost > 0 {
+		base.MaxConnsPerHost = profile.MaxConnsPerHost
+	}
+	if profile.IdleConnTimeout > 0 {
+		base.IdleConnTimeout = profile.IdleConnTimeout
+	}
+
+	var roundTripper http.RoundTripper = base
+	// 录制模式: 把每个 Provider 的上游请求保存到 $LYRICS_HTTP_RECORD/<name>.json, 用作测试 fixture
+	if dir := os.Getenv("LYRICS_HTTP_RECORD"); dir != "" {
+		if name == "" {
+			name = "default"
+		}
+		roundTripper = &replay.Recorder{Base: base, Path: filepath.Join(dir, name+".json")}
+	}
+
+	transport := NewTransport(roundTripper, config.C.HTTP)
+	transport.limits = sharedLimits
+	transport.UserAgent = profile.UserAgent
+	transport.Headers = profile.Headers
+	return &http.Client{Transport: transport, Timeout: config.C.HTTP.Timeout}, nil
+}
@@ -0,0 +1,159 @@
+package app_utils
+
+import (
+	"encoding/pem"
+	"io"
+	"lyrics/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoServer 把收到的 User-Agent / X-Test 请求头和 Host 写回响应
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("User-Agent")+"|"+r.Header.Get("X-Test")+"|"+r.Host)
+	}))
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestNewClientProfile(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	outbound := config.OutboundConfig{
+		Default: config.OutboundProfile{UserAgent: "default-agent", Headers: map[string]string{"X-Test": "default"}},
+		Providers: map[string]config.OutboundProfile{
+			"NetEaseLK":     {Headers: map[string]string{"X-Test": "provider"}},
+			"QQMusicLyrics": {UserAgent: "qq-agent"},
+		},
+	}
+	cases := []struct {
+		name string
+		want string
+	}{
+		// 没有覆盖时使用 Default
+		{"KugouMusic", "default-agent|default|"},
+		// 内置的 UA 覆盖 Default
+		{"NetEaseMusic", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15|default|"},
+		// 配置的请求头覆盖 Default, 内置的 UA 保留
+		{"NetEaseLK", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15|provider|"},
+		{"QQMusicLyrics", "qq-agent|default|"},
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	for _, c := range cases {
+		client, err := newClient(c.name, outbound.Profile(c.name))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := get(t, client, server.URL); got != c.want+host {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want+host)
+		}
+	}
+
+	// 配置的 UA 优先于内置的 UA
+	outbound.Providers["NetEaseMusic"] = config.OutboundProfile{UserAgent: "configured-agent"}
+	client, err := newClient("NetEaseMusic", outbound.Profile("NetEaseMusic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, client, server.URL); got != "configured-agent|default|"+host {
+		t.Errorf("configured NetEaseMusic: got %q", got)
+	}
+
+	// 请求自己设置的请求头不被覆盖
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "request-agent")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := string(body); got != "request-agent|default|"+host {
+		t.Errorf("request header: got %q", got)
+	}
+}
+
+func TestNewClientProxy(t *testing.T) {
+	proxy := echoServer()
+	defer proxy.Close()
+
+	client, err := newClient("Proxied", config.OutboundProfile{Proxy: proxy.URL, UserAgent: "agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 请求发给代理, Host 仍是原来的地址
+	if got := get(t, client, "http://lyrics.invalid/search"); got != "agent||lyrics.invalid" {
+		t.Errorf("proxy: got %q", got)
+	}
+
+	client, err = newClient("Direct", config.OutboundProfile{Proxy: "direct"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base := client.Transport.(*Transport).Base.(*http.Transport); base.Proxy != nil {
+		t.Error("direct: proxy is set")
+	}
+}
+
+func TestNewClientCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newClient("Trusted", config.OutboundProfile{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// httptest 的自签名证书只有加载了 CAFile 才能通过校验
+	if got := get(t, client, server.URL); got != "ok" {
+		t.Errorf("trusted: got %q", got)
+	}
+}
+
+func TestNewClientErrors(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		profile config.OutboundProfile
+		want    string
+	}{
+		{"bad proxy url", config.OutboundProfile{Proxy: "http://[::1"}, "missing ']'"},
+		{"unsupported proxy scheme", config.OutboundProfile{Proxy: "ftp://proxy.invalid"}, `unsupported proxy scheme "ftp"`},
+		{"missing ca file", config.OutboundProfile{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, "no such file"},
+		{"no certificate in ca file", config.OutboundProfile{CAFile: empty}, "no certificate found"},
+	}
+	for _, c := range cases {
+		client, err := newClient(c.name, c.profile)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.name, err, c.want)
+		}
+		if client != nil {
+			t.Errorf("%s: client is not nil", c.name)
+		}
+	}
+}
@@ -11,7 +11,8 @@ import (
 	"net/http"
 )
 
-var C = http.DefaultClient
+// C 使用默认出站设置的客户端, Provider 应该通过 Client(name) 获取自己的客户端
+var C = Client("")
 
 func FromGinPostJson[T any](c *gin.Context) T {
 	var search T
@@ -22,18 +23,23 @@ func FromGinPostJson[T any](c *gin.Context) T {
 	return search
 }
 
-func HttpGet[T any](urlRedirect string, headers map[string]string) (T, error) {
+func HttpGet[T any](client *http.Client, urlRedirect string, headers map[string]string) (T, error) {
+	var data T
 	req, err := http.NewRequest("GET", urlRedirect, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+	if err != nil {
+		return data, err
+	}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
-	var data T
-	resp, err := C.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return data, err
 	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		return data, errors.New(fmt.Sprintf("Search [%s] API Response %d", urlRedirect, resp.StatusCode))
 	}
@@ -50,7 +56,7 @@ func HttpGet[T any](urlRedirect string, headers map[string]string) (T, error) {
 	return data, nil
 }
 
-func HttpPost[T any, R any](form R, url string, headers map[string]string) (T, error) {
+func HttpPost[T any, R any](client *http.Client, form R, url string, headers map[string]string) (T, error) {
 	var data T
 	body, err := json.Marshal(form)
 	if err != nil {
@@ -59,14 +65,19 @@ func HttpPost[T any, R any](form R, url string, headers map[string]string) (T, e
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/57.0.2987.110 Safari/537.36")
+	if err != nil {
+		return data, err
+	}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	resp, err := C.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return data, err
 	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		return data, errors.New(fmt.Sprintf("Search [%s, %s] API Response %d", url, body, resp.StatusCode))
 	}
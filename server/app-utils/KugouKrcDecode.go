@@ -2,16 +2,18 @@ package app_utils
 
 import (
 	"bytes"
-	"compress/gzip"
+	"compress/zlib"
+	"errors"
 	"io"
 )
 
 var decodeKey = []byte{64, 71, 97, 119, 94, 50, 116, 71, 81, 54, 49, 45, 206, 210, 110, 105}
 var flagKey = []byte("krc1")
 
-func decryptKugouKrc(data []byte) (string, error) {
+// DecryptKugouKrc 解密酷狗 KRC: "krc1" 头 + 异或加密的 zlib 数据
+func DecryptKugouKrc(data []byte) (string, error) {
 	if !bytes.HasPrefix(data, flagKey) {
-		return "", nil
+		return "", errors.New("invalid krc magic")
 	}
 
 	decrypted := make([]byte, len(data)-len(flagKey))
@@ -19,16 +21,15 @@ func decryptKugouKrc(data []byte) (string, error) {
 		decrypted[i] = b ^ decodeKey[i&0b1111]
 	}
 
-	reader := bytes.NewReader(decrypted)
-	gzipReader, err := gzip.NewReader(reader)
+	zlibReader, err := zlib.NewReader(bytes.NewReader(decrypted))
 	if err != nil {
-		return "", err // handle gzip reader creation failure
+		return "", err
 	}
-	defer func(gzipReader *gzip.Reader) {
-		_ = gzipReader.Close()
-	}(gzipReader)
+	defer func(zlibReader io.ReadCloser) {
+		_ = zlibReader.Close()
+	}(zlibReader)
 
-	unarchivedData, err := io.ReadAll(gzipReader)
+	unarchivedData, err := io.ReadAll(zlibReader)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,258 @@
+package app_utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"lyrics/config"
+	"lyrics/ratelimit"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrBodyTooLarge = errors.New("response body exceeds max_body")
+
+// Transport 在 Base 之上做按 host 的并发 / QPS 限制, 网络错误和 429/5xx 的重试, 以及响应体大小限制
+type Transport struct {
+	Base   http.RoundTripper
+	Config config.HTTPConfig
+	// 请求没有设置时补上的 User-Agent 和请求头
+	UserAgent string
+	Headers   map[string]string
+
+	limits *hostLimits
+}
+
+// hostLimits 按 host 的并发和 QPS 限制, 同一进程内的所有客户端共用
+type hostLimits struct {
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+	qps   *ratelimit.Limiter
+}
+
+func NewTransport(base http.RoundTripper, conf config.HTTPConfig) *Transport {
+	return &Transport{Base: base, Config: conf, limits: newHostLimits(conf)}
+}
+
+func newHostLimits(conf config.HTTPConfig) *hostLimits {
+	limits := &hostLimits{hosts: map[string]chan struct{}{}}
+	if conf.PerHostQPS > 0 {
+		limits.qps = ratelimit.New(conf.PerHostQPS, conf.PerHostBurst)
+	}
+	return limits
+}
+
+type retryKey struct{}
+
+// AllowRetry 标记 POST 等非幂等方法的请求可以重试, 只用于只读的查询 (如 NetEase 用 POST 的搜索和歌词接口)
+func AllowRetry(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), retryKey{}, true))
+}
+
+// retrySafe 只有 GET / HEAD / OPTIONS 和明确标记过的请求会重试, 避免重复发布等副作用
+func retrySafe(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	allowed, _ := req.Context().Value(retryKey{}).(bool)
+	return allowed
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = t.withHeaders(req)
+	// 非幂等请求, 以及有请求体但无法重放的请求只请求一次
+	replayable := retrySafe(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		release, err := t.acquire(r.Context(), r.URL.Host)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.Base.RoundTrip(r)
+		if err != nil {
+			release()
+		} else {
+			resp.Body = &limitedBody{ReadCloser: resp.Body, max: t.Config.MaxBody, release: release}
+		}
+
+		wait, retry := t.retryable(resp, err, attempt)
+		if !retry || !replayable || attempt >= t.Config.Retries {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		log.Printf("[INFO] Retry %s %s In %s (attempt %d)", req.Method, req.URL.Redacted(), wait.Round(time.Millisecond), attempt+1)
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// withHeaders 补上默认请求头, RoundTripper 不能修改传入的请求, 需要时复制一份
+func (t *Transport) withHeaders(req *http.Request) *http.Request {
+	cloned := false
+	set := func(k, v string) {
+		if v == "" || req.Header.Get(k) != "" {
+			return
+		}
+		if !cloned {
+			req = req.Clone(req.Context())
+			cloned = true
+		}
+		req.Header.Set(k, v)
+	}
+	set("User-Agent", t.UserAgent)
+	for k, v := range t.Headers {
+		set(k, v)
+	}
+	return req
+}
+
+// retryable 判断是否需要重试以及等待时间, Retry-After 超过 MaxBackoff 时直接返回结果
+func (t *Transport) retryable(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		var dns *net.DNSError
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &dns) && dns.IsNotFound) {
+			return 0, false
+		}
+		return t.backoff(attempt), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+	if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		if t.Config.MaxBackoff > 0 && after > t.Config.MaxBackoff {
+			return 0, false
+		}
+		return after, true
+	}
+	return t.backoff(attempt), true
+}
+
+// backoff 指数退避, 实际等待时间在 [d/2, d) 之间随机
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.Config.Backoff << min(attempt, 16)
+	if t.Config.MaxBackoff > 0 && d > t.Config.MaxBackoff {
+		d = t.Config.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// acquire 占用 host 的并发名额并等待 QPS 令牌, 返回的函数用于释放名额
+func (t *Transport) acquire(ctx context.Context, host string) (func(), error) {
+	release := func() {}
+	if t.Config.PerHostConcurrency > 0 {
+		t.limits.mu.Lock()
+		sem, ok := t.limits.hosts[host]
+		if !ok {
+			sem = make(chan struct{}, t.Config.PerHostConcurrency)
+			t.limits.hosts[host] = sem
+		}
+		t.limits.mu.Unlock()
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() {
+			once.Do(func() { <-sem })
+		}
+	}
+
+	if t.limits.qps != nil {
+		for {
+			ok, wait := t.limits.qps.Allow(host)
+			if ok {
+				break
+			}
+			if err := sleep(ctx, wait); err != nil {
+				release()
+				return nil, err
+			}
+		}
+	}
+	return release, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedBody 超过 max 字节后返回 ErrBodyTooLarge (max 为 0 时不限制), 读完或关闭时释放 host 的并发名额
+type limitedBody struct {
+	io.ReadCloser
+	max     int64
+	read    int64
+	release func()
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.max > 0 {
+		if b.read > b.max {
+			return 0, ErrBodyTooLarge
+		}
+		if rest := b.max - b.read + 1; int64(len(p)) > rest {
+			p = p[:rest]
+		}
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.max > 0 && b.read > b.max {
+		return n - int(b.read-b.max), ErrBodyTooLarge
+	}
+	if errors.Is(err, io.EOF) {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	b.release()
+	return b.ReadCloser.Close()
+}
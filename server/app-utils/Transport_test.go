@@ -0,0 +1,53 @@
+package app_utils
+
+import (
+	"lyrics/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportRetriesOnlySafeRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport, config.HTTPConfig{
+		Timeout: 5 * time.Second, Retries: 2, Backoff: time.Millisecond, MaxBackoff: time.Millisecond,
+	})}
+	cases := []struct {
+		name  string
+		req   func() *http.Request
+		calls int32
+	}{
+		{"get", func() *http.Request {
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			return req
+		}, 3},
+		{"post", func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("a=1"))
+			return req
+		}, 1},
+		{"marked post", func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("a=1"))
+			return AllowRetry(req)
+		}, 3},
+	}
+	for _, c := range cases {
+		calls.Store(0)
+		resp, err := client.Do(c.req())
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		resp.Body.Close()
+		if calls.Load() != c.calls {
+			t.Errorf("%s: %d calls, want %d", c.name, calls.Load(), c.calls)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件路径, 可通过 LYRICS_CONFIG 覆盖; 文件不存在时全部使用默认值
+var path = "./config.yaml"
+
+type Config struct {
+	Listen string `yaml:"listen"`
+	// 反向代理地址, 只有来自这些地址的 X-Forwarded-For 才会被信任
+	TrustedProxies []string        `yaml:"trusted_proxies"`
+	Auth           AuthConfig      `yaml:"auth"`
+	RateLimit      RateLimitConfig `yaml:"rate_limit"`
+	LRCLIB         LRCLIBConfig    `yaml:"lrclib"`
+	Providers      ProvidersConfig `yaml:"providers"`
+	HTTP           HTTPConfig      `yaml:"http"`
+	Outbound       OutboundConfig  `yaml:"outbound"`
+	Cleanup        CleanupConfig   `yaml:"cleanup"`
+	NoLyrics       NoLyricsConfig  `yaml:"no_lyrics"`
+	Consensus      ConsensusConfig `yaml:"consensus"`
+	Profanity      ProfanityConfig `yaml:"profanity"`
+}
+
+// ProfanityConfig 返回歌词前屏蔽脏话, 用户设置和请求中的 mask 可以覆盖 enabled
+type ProfanityConfig struct {
+	// 默认是否屏蔽
+	Enabled bool `yaml:"enabled"`
+	// 使用哪些语言的词表, 为空时全部使用
+	Languages []string `yaml:"languages"`
+	// 按语言追加的屏蔽词, 以 * 结尾的拉丁字母词按词首匹配
+	Words map[string][]string `yaml:"words"`
+	// 替换用的字符, 默认 *
+	Mask string `yaml:"mask"`
+}
+
+// ConsensusConfig 根据同一首歌多个候选的时间轴估计 offset
+type ConsensusConfig struct {
+	// 开启后把满足条件的估计直接写入候选的 offset, 否则只在 estimate 中给出建议
+	AutoApply     bool    `yaml:"auto_apply"`
+	MinConfidence float64 `yaml:"min_confidence"`
+	// 估计的 offset 小于这个值时不自动应用
+	MinShift time.Duration `yaml:"min_shift"`
+}
+
+// NoLyricsConfig 纯音乐 / 没有找到歌词时保存的结果多久之后重新搜索
+type NoLyricsConfig struct {
+	InstrumentalRetry time.Duration `yaml:"instrumental_retry"`
+	NotFoundRetry     time.Duration `yaml:"not_found_retry"`
+	// 至少这么多 Provider 成功返回 (Provider 不足时要求全部成功) 才保存 not_found, 避免一个 Provider 的结果决定
+	MinProviders int `yaml:"min_providers"`
+}
+
+// CleanupConfig 返回歌词前的清理, 请求中 raw 为 true 时跳过; 保存的歌词不受影响
+type CleanupConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 提取并去掉开头的 作词 / 作曲 / 编曲 / 制作人 等署名行和 [by:] 标签
+	Credits bool `yaml:"credits"`
+	// 去掉多余的空行
+	Blank bool `yaml:"blank"`
+	// 广告 / 版权声明的正则, 匹配到的行整行去掉
+	Ads []string `yaml:"ads"`
+}
+
+// HTTPConfig 请求上游时的重试和限流
+type HTTPConfig struct {
+	// 整个请求 (包括重试) 的超时
+	Timeout time.Duration `yaml:"timeout"`
+	// 网络错误 / 429 / 5xx 的最大重试次数
+	Retries int `yaml:"retries"`
+	// 第一次重试前的等待时间, 之后翻倍并加入随机抖动, 最多 MaxBackoff
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// 每个 host 同时进行的请求数和每秒请求数
+	PerHostConcurrency int     `yaml:"per_host_concurrency"`
+	PerHostQPS         float64 `yaml:"per_host_qps"`
+	PerHostBurst       int     `yaml:"per_host_burst"`
+	// 响应体的最大字节数
+	MaxBody int64 `yaml:"max_body"`
+}
+
+type AuthConfig struct {
+	// 为 true 时所有接口都必须携带有效 token, 否则匿名请求使用共享的默认选择
+	Required bool `yaml:"required"`
+	// 引导用的管理员 token, 用于创建第一个用户
+	AdminToken string `yaml:"admin_token"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 读取缓存 / 其他接口的预算
+	Read Budget `yaml:"read"`
+	// 需要请求上游的搜索预算
+	Search Budget `yaml:"search"`
+	// 不受限制的 IP / CIDR / 用户名
+	Allowlist []string `yaml:"allowlist"`
+}
+
+type Budget struct {
+	PerMinute float64 `yaml:"per_minute"`
+	Burst     int     `yaml:"burst"`
+}
+
+type LRCLIBConfig struct {
+	// 查询地址, 可以指向自建的 LRCLIB 兼容服务
+	BaseURL string `yaml:"base_url"`
+	// 开启后允许把确认 / 上传的歌词发布到 PublishURL
+	Publish    bool   `yaml:"publish"`
+	PublishURL string `yaml:"publish_url"`
+}
+
+type ProvidersConfig struct {
+	// 声明式 Provider 定义文件 (glob), 每个文件可以是一个定义或者定义列表
+	Files []string `yaml:"files"`
+	// 直接写在配置里的声明式 Provider
+	Declarative []DeclarativeProvider `yaml:"declarative"`
+	// 外部进程插件
+	Plugins []PluginProvider `yaml:"plugins"`
+	Breaker BreakerConfig    `yaml:"breaker"`
+}
+
+// OutboundConfig 出站连接设置, Providers 按 Provider 名字 (与 /admin/providers/health 中一致) 覆盖 Default
+type OutboundConfig struct {
+	Default   OutboundProfile            `yaml:"default"`
+	Providers map[string]OutboundProfile `yaml:"providers"`
+}
+
+type OutboundProfile struct {
+	// http:// https:// socks5:// 代理; 为空时使用 HTTP_PROXY 等环境变量, direct 表示不使用代理
+	Proxy     string            `yaml:"proxy"`
+	UserAgent string            `yaml:"user_agent"`
+	Headers   map[string]string `yaml:"headers"`
+	// 额外信任的 CA 证书 (PEM), 用于企业代理等场景
+	CAFile              string        `yaml:"ca_file"`
+	MaxIdleConns        int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`
+}
+
+// 内置的 Provider 默认值, 优先级在 Default 之上, 配置中的 Providers 之下
+var builtinProfiles = map[string]OutboundProfile{
+	"NetEaseMusic": {UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15"},
+	"NetEaseLK":    {UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15"},
+}
+
+// Profile 合并出某个 Provider 最终使用的出站设置
+func (o OutboundConfig) Profile(name string) OutboundProfile {
+	profile := o.Default
+	profile.Headers = map[string]string{}
+	for k, v := range o.Default.Headers {
+		profile.Headers[k] = v
+	}
+	for _, override := range []OutboundProfile{builtinProfiles[name], o.Providers[name]} {
+		if override.Proxy != "" {
+			profile.Proxy = override.Proxy
+		}
+		if override.UserAgent != "" {
+			profile.UserAgent = override.UserAgent
+		}
+		for k, v := range override.Headers {
+			profile.Headers[k] = v
+		}
+		if override.CAFile != "" {
+			profile.CAFile = override.CAFile
+		}
+		if override.MaxIdleConns > 0 {
+			profile.MaxIdleConns = override.MaxIdleConns
+		}
+		if override.MaxIdleConnsPerHost > 0 {
+			profile.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+		}
+		if override.MaxConnsPerHost > 0 {
+			profile.MaxConnsPerHost = override.MaxConnsPerHost
+		}
+		if override.IdleConnTimeout > 0 {
+			profile.IdleConnTimeout = override.IdleConnTimeout
+		}
+	}
+	return profile
+}
+
+// BreakerConfig 连续失败达到 Failures 次后熔断 Cooldown, 之后放行一次探测请求
+type BreakerConfig struct {
+	Failures int           `yaml:"failures"`
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// PluginProvider 由外部可执行文件实现的歌词源
+//
+// oneshot: 每次搜索启动一次进程, stdin 写入 SearchRequest JSON, stdout 返回 MusicRelation 数组
+// jsonl: 常驻进程, 每行一个带 request_id 的 SearchRequest, 每行返回 {"request_id", "results": MusicRelation 数组}, 崩溃或超时后自动重启
+type PluginProvider struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+	Dir     string            `yaml:"dir"`
+	// oneshot (默认) / jsonl
+	Mode string `yaml:"mode"`
+	// 单次搜索超时, 默认 10s
+	Timeout time.Duration `yaml:"timeout"`
+	// 单次输出的最大字节数, 默认 4MiB
+	MaxOutput int `yaml:"max_output"`
+}
+
+// DeclarativeProvider 通过 HTTP/JSON 定义的歌词源
+//
+// URL / Body 模板支持 {{name}} {{singer}} {{album}} {{query}} {{duration}} (ms) {{duration_s}}, 第二步请求还可以使用 {{id}}
+type DeclarativeProvider struct {
+	Name   string            `yaml:"name"`
+	Search DeclarativeSearch `yaml:"search"`
+	// 字段选择器: id / title / artist / duration / lyrics / trans
+	Fields map[string]string `yaml:"fields"`
+	// 可选的第二步请求, 用搜索结果的 id 获取歌词
+	Fetch *DeclarativeRequest `yaml:"fetch"`
+	// 歌词编码: plain (默认) / base64
+	Decode string `yaml:"decode"`
+	// 最多处理的搜索结果数, 默认 10
+	Limit int `yaml:"limit"`
+}
+
+type DeclarativeSearch struct {
+	DeclarativeRequest `yaml:",inline"`
+	// 搜索结果列表的选择器, 例如 data.songs 或 result[*]
+	Items string `yaml:"items"`
+}
+
+type DeclarativeRequest struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// 第二步请求返回内容的字段选择器, 覆盖同名的 Fields
+	Fields map[string]string `yaml:"fields"`
+}
+
+var C = load()
+
+func load() Config {
+	conf := Config{
+		Listen: "[::]:8331",
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Read:    Budget{PerMinute: 120, Burst: 30},
+			Search:  Budget{PerMinute: 6, Burst: 5},
+		},
+		LRCLIB: LRCLIBConfig{
+			BaseURL: "https://lrclib.net",
+		},
+		HTTP: HTTPConfig{
+			Timeout:            20 * time.Second,
+			Retries:            2,
+			Backoff:            300 * time.Millisecond,
+			MaxBackoff:         5 * time.Second,
+			PerHostConcurrency: 4,
+			PerHostQPS:         10,
+			PerHostBurst:       10,
+			MaxBody:            8 << 20,
+		},
+		Outbound: OutboundConfig{
+			Default: OutboundProfile{
+				UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			},
+		},
+		Providers: ProvidersConfig{
+			Breaker: BreakerConfig{Failures: 5, Cooldown: time.Minute},
+		},
+		Profanity: ProfanityConfig{
+			Mask: "*",
+		},
+		Consensus: ConsensusConfig{
+			MinConfidence: 0.6,
+			MinShift:      300 * time.Millisecond,
+		},
+		NoLyrics: NoLyricsConfig{
+			InstrumentalRetry: 30 * 24 * time.Hour,
+			NotFoundRetry:     24 * time.Hour,
+			MinProviders:      2,
+		},
+		Cleanup: CleanupConfig{
+			Enabled: true,
+			Credits: true,
+			Blank:   true,
+			// 只匹配整行声明, 避免去掉 "未经你许可" 这样的歌词
+			Ads: []string{
+				`^[\s\p{P}]*未经.{0,12}许可[\s\p{P}]*.{0,6}(不得|禁止|请勿).{0,20}$`,
+				`^[\s\p{P}]*未經.{0,12}許可[\s\p{P}]*.{0,6}(不得|禁止|請勿).{0,20}$`,
+				`^[\s\p{P}]*(不得|禁止|请勿|請勿)翻(唱|录|錄).{0,16}$`,
+				`^[\s\p{P}]*(著作权|著作權)(人|所有|归|歸).{0,24}$`,
+				`^(.{0,24}(版权所有|版權所有)[\s\p{P}]*|[\s\p{P}]*(版权所有|版權所有).{0,24})$`,
+				`(?i)^[\s\p{P}]*(QQ音乐|酷狗音乐|网易云音乐|酷我音乐|TME)\s*享有.{0,24}$`,
+			},
+		},
+	}
+
+	if p := os.Getenv("LYRICS_CONFIG"); p != "" {
+		path = p
+	}
+	content, err := os.ReadFile(path)
+	if err == nil {
+		if err := yaml.Unmarshal(content, &conf); err != nil {
+			log.Fatalf("[ERROR] Failed Parse Config %s: %s", path, err)
+		}
+		log.Printf("[INFO] Config Loaded From %s", path)
+	} else if !os.IsNotExist(err) {
+		log.Fatalf("[ERROR] Failed Read Config %s: %s", path, err)
+	}
+
+	if token := os.Getenv("LYRICS_ADMIN_TOKEN"); token != "" {
+		conf.Auth.AdminToken = token
+	}
+	conf.Providers.Declarative = append(conf.Providers.Declarative, loadDefinitions(conf.Providers.Files)...)
+	if conf.LRCLIB.PublishURL == "" {
+		conf.LRCLIB.PublishURL = conf.LRCLIB.BaseURL
+	}
+	return conf
+}
+
+func loadDefinitions(patterns []string) []DeclarativeProvider {
+	var result []DeclarativeProvider
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Fatalf("[ERROR] Invalid Provider Pattern %s: %s", pattern, err)
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				log.Fatalf("[ERROR] Failed Read Provider %s: %s", file, err)
+			}
+			var list []DeclarativeProvider
+			if err := yaml.Unmarshal(content, &list); err != nil {
+				var single DeclarativeProvider
+				if err := yaml.Unmarshal(content, &single); err != nil {
+					log.Fatalf("[ERROR] Failed Parse Provider %s: %s", file, err)
+				}
+				list = []DeclarativeProvider{single}
+			}
+			log.Printf("[INFO] Provider Definitions Loaded From %s", file)
+			result = append(result, list...)
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package lyric
+
+import "strings"
+
+// 翻译 / 罗马音与原文时间戳默认允许的误差 (ms)
+const BilingualTolerance = 500
+
+type BilingualLine struct {
+	Time        int64  `json:"time"`
+	End         int64  `json:"end"`
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+	Roma        string `json:"roma,omitempty"`
+}
+
+// Bilingual 把原文的每一行与翻译 / 罗马音配对.
+// 原文中同一时间戳的连续几行 (NetEase 的 LRC 会把翻译插在原文下一行) 第一行为原文, 其余作为翻译;
+// trans / roma 中的行按开始时间对齐到误差不超过 tolerance 的最近一行, 有 trans 时优先使用 trans
+func Bilingual(main Lyric, trans Lyric, roma Lyric, tolerance int64) []BilingualLine {
+	var originals Lyric
+	var inline []string
+	for _, line := range main.Lines {
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		if n := len(originals.Lines); n > 0 && originals.Lines[n-1].Start == line.Start {
+			originals.Lines[n-1].End = max(originals.Lines[n-1].End, line.End)
+			inline[n-1] = joinText(inline[n-1], line.Text)
+			continue
+		}
+		originals.Lines = append(originals.Lines, line)
+		inline = append(inline, "")
+	}
+
+	translations := pairLines(originals, trans, tolerance)
+	romas := pairLines(originals, roma, tolerance)
+	result := make([]BilingualLine, len(originals.Lines))
+	for i, line := range originals.Lines {
+		translation := translations[i]
+		if translation == "" {
+			translation = inline[i]
+		}
+		result[i] = BilingualLine{Time: line.Start, End: line.End, Text: line.Text, Translation: translation, Roma: romas[i]}
+	}
+	return result
+}
+
+// pairLines 返回与 originals 一一对应的文字, 对齐到同一行的多行用空格连接;
+// 空行和 QQ 的 "//" 占位会被跳过, 同一时间戳的多行只取第一行 (按原文生成的罗马音也包含插入的翻译行)
+func pairLines(originals Lyric, layer Lyric, tolerance int64) []string {
+	result := make([]string, len(originals.Lines))
+	last := int64(-1)
+	for _, line := range layer.Lines {
+		text := strings.TrimSpace(line.Text)
+		if text == "" || text == "//" || line.Start == last {
+			continue
+		}
+		last = line.Start
+		if i, ok := originals.Nearest(line.Start, tolerance); ok && text != originals.Lines[i].Text {
+			result[i] = joinText(result[i], text)
+		}
+	}
+	return result
+}
+
+func joinText(a string, b string) string {
+	if a == "" {
+		return strings.TrimSpace(b)
+	}
+	return a + " " + strings.TrimSpace(b)
+}
@@ -0,0 +1,61 @@
+package lyric
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBilingual(t *testing.T) {
+	cases := []struct {
+		name        string
+		main, trans string
+		roma        string
+		want        []BilingualLine
+	}{
+		{
+			// 翻译时间戳与原文相差在误差之内时对齐到最近的一行, 超出误差的行丢弃
+			name:  "tolerance",
+			main:  "[00:01.00]一\n[00:05.00]二\n[00:09.00]三",
+			trans: "[00:01.30]one\n[00:04.60]two\n[00:10.00]three",
+			want: []BilingualLine{
+				{Time: 1000, End: 5000, Text: "一", Translation: "one"},
+				{Time: 5000, End: 9000, Text: "二", Translation: "two"},
+				{Time: 9000, End: 14000, Text: "三"},
+			},
+		},
+		{
+			// NetEase 把翻译插在同一时间戳的下一行
+			name: "inline translation",
+			main: "[00:01.00]君が好き\n[00:01.00]喜欢你\n[00:04.00]さよなら\n[00:04.00]再见",
+			want: []BilingualLine{
+				{Time: 1000, End: 4000, Text: "君が好き", Translation: "喜欢你"},
+				{Time: 4000, End: 9000, Text: "さよなら", Translation: "再见"},
+			},
+		},
+		{
+			// 单独的翻译优先于插入的翻译
+			name:  "trans overrides inline",
+			main:  "[00:01.00]君が好き\n[00:01.00]喜欢你",
+			trans: "[00:01.00]我喜欢你",
+			want:  []BilingualLine{{Time: 1000, End: 6000, Text: "君が好き", Translation: "我喜欢你"}},
+		},
+		{
+			// QQ 的 "//" 占位和空行不作为翻译; 罗马音中同一时间戳的翻译行只取第一行
+			name:  "placeholders",
+			main:  "[00:01.00]愛してる\n[00:03.00]\n[00:05.00]Oh yeah",
+			trans: "[00:01.00]我爱你\n[00:05.00]//",
+			roma:  "[00:01.00]ai shiteru\n[00:01.00]wo ai ni\n[00:05.00]Oh yeah",
+			want: []BilingualLine{
+				{Time: 1000, End: 3000, Text: "愛してる", Translation: "我爱你", Roma: "ai shiteru"},
+				{Time: 5000, End: 10000, Text: "Oh yeah"},
+			},
+		},
+	}
+	// 最后一行没有结束时间时按 defaultLineDuration 计
+	for _, c := range cases {
+		got := Bilingual(Parse(c.main), Parse(c.trans), Parse(c.roma), BilingualTolerance)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", c.name, got, c.want)
+		}
+	}
+}
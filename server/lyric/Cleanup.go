@@ -0,0 +1,149 @@
+package lyric
+
+import (
+	"lyrics/model"
+	"regexp"
+	"strings"
+)
+
+// CleanOptions 清理歌词时要去掉的内容
+type CleanOptions struct {
+	// 提取并去掉开头 (第一行歌词之前) 的署名行 (作词 : xxx) 和 [by:] 标签
+	Credits bool
+	// 去掉无文字的行, 但保留标记上一行结束的第一个空行
+	Blank bool
+	// 匹配到的行 (广告 / 版权声明) 整行去掉, 正则应当匹配整行声明而不是其中几个字
+	Ads []*regexp.Regexp
+}
+
+// 署名行: 标签 : 内容, 标签最长 16 个字符
+var creditLine = regexp.MustCompile(`^([^:：]{1,16}?)\s*[:：]\s*(.+)$`)
+
+// 署名标签 (去掉空格并转为小写) 对应的字段, 空字符串表示只记录在 Other 中
+var creditRoles = map[string]string{
+	"作词": "lyricist", "作詞": "lyricist", "词": "lyricist", "詞": "lyricist", "填词": "lyricist", "填詞": "lyricist",
+	"lyricist": "lyricist", "lyrics": "lyricist", "lyricsby": "lyricist", "writtenby": "lyricist", "作詞者": "lyricist", "작사": "lyricist",
+	"作曲": "composer", "曲": "composer", "composer": "composer", "composedby": "composer", "musicby": "composer", "作曲者": "composer", "작곡": "composer",
+	"编曲": "arranger", "編曲": "arranger", "arranger": "arranger", "arrangedby": "arranger", "編曲者": "arranger", "편곡": "arranger",
+	"制作人": "producer", "製作人": "producer", "监制": "producer", "監製": "producer", "producer": "producer", "producedby": "producer", "프로듀서": "producer",
+	"词曲": "", "詞曲": "", "制作": "", "製作": "", "出品": "", "出品人": "", "发行": "", "發行": "", "出品方": "",
+	"演唱": "", "原唱": "", "歌手": "", "和声": "", "和聲": "", "和音": "", "配唱": "", "人声": "", "人聲": "", "合唱": "",
+	"混音": "", "混音师": "", "混音師": "", "母带": "", "母帶": "", "母带处理": "", "录音": "", "錄音": "", "录音师": "", "錄音師": "", "录音室": "", "錄音室": "",
+	"吉他": "", "贝斯": "", "貝斯": "", "鼓": "", "键盘": "", "鍵盤": "", "钢琴": "", "鋼琴": "", "弦乐": "", "弦樂": "", "弦乐编写": "",
+	"统筹": "", "統籌": "", "企划": "", "企劃": "", "策划": "", "策劃": "", "监唱": "", "監唱": "", "op": "", "sp": "", "isrc": "",
+	"mix": "", "mixedby": "", "mastering": "", "masteredby": "", "recording": "", "recordedby": "", "vocal": "", "vocals": "",
+	"guitar": "", "bass": "", "drums": "", "keyboard": "", "piano": "", "strings": "", "歌词制作": "", "歌詞製作": "", "lrc": "", "lrc编辑": "",
+}
+
+// Clean 按 options 清理歌词文本, 提取到的署名写入 credits (已有的字段不覆盖)
+func Clean(content string, options CleanOptions, credits *model.LyricCredits) string {
+	var result []string
+	// 已经输出了有文字的行, 以及最后输出的是不是空行
+	started, blank := false, false
+	for _, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			if !options.Blank {
+				result = append(result, raw)
+			}
+			continue
+		}
+
+		if m := tagLine.FindStringSubmatch(line); m != nil {
+			if options.Credits && strings.EqualFold(m[1], "by") {
+				setCredit(credits, "uploader", "by", strings.TrimSpace(m[2]))
+				continue
+			}
+			result = append(result, raw)
+			continue
+		}
+
+		text := lineText(line)
+		if strings.TrimSpace(text) == "" {
+			if options.Blank && (!started || blank) {
+				continue
+			}
+			blank = true
+			result = append(result, raw)
+			continue
+		}
+		// 歌词中间的 "词 : xxx" 可能是歌词本身, 只处理开头的署名
+		if options.Credits && !started {
+			if label, value, ok := credit(text); ok {
+				setCredit(credits, creditRoles[normalizeLabel(label)], label, value)
+				continue
+			}
+		}
+		if matchAny(options.Ads, text) {
+			continue
+		}
+		started, blank = true, false
+		result = append(result, raw)
+	}
+	return strings.Join(result, "\n")
+}
+
+// lineText 去掉时间戳和逐字时间后的文字, NetEase 的 JSON 署名行取 tx 拼接的内容
+func lineText(line string) string {
+	if strings.HasPrefix(line, "{") {
+		if parsed, ok := parseJsonLine(line); ok {
+			return parsed.Text
+		}
+	}
+	texts, _ := splitMarkup(line)
+	return strings.Join(texts, "")
+}
+
+func credit(text string) (string, string, bool) {
+	m := creditLine.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", "", false
+	}
+	if _, ok := creditRoles[normalizeLabel(m[1])]; !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(m[1]), strings.TrimSpace(m[2]), true
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), ""))
+}
+
+func setCredit(credits *model.LyricCredits, role string, label string, value string) {
+	if credits == nil || value == "" {
+		return
+	}
+	var field *string
+	switch role {
+	case "uploader":
+		field = &credits.Uploader
+	case "lyricist":
+		field = &credits.Lyricist
+	case "composer":
+		field = &credits.Composer
+	case "arranger":
+		field = &credits.Arranger
+	case "producer":
+		field = &credits.Producer
+	default:
+		if credits.Other == nil {
+			credits.Other = map[string]string{}
+		}
+		if _, ok := credits.Other[label]; !ok {
+			credits.Other[label] = value
+		}
+		return
+	}
+	if *field == "" {
+		*field = value
+	}
+}
+
+func matchAny(patterns []*regexp.Regexp, text string) bool {
+	for _, p := range patterns {
+		if p.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
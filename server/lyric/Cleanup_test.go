@@ -0,0 +1,52 @@
+package lyric
+
+import (
+	"lyrics/config"
+	"lyrics/model"
+	"regexp"
+	"testing"
+)
+
+// 只有第一行歌词之前的署名会被提取
+func TestCleanCredits(t *testing.T) {
+	content := "[00:00.00]作词 : 方文山\n[00:01.00]作曲 : 周杰伦\n[00:12.00]故事的小黄花\n[00:20.00]词: 是你写给我的诗\n[00:25.00]制作人 : 不是署名"
+	var credits model.LyricCredits
+	got := Clean(content, CleanOptions{Credits: true}, &credits)
+	want := "[00:12.00]故事的小黄花\n[00:20.00]词: 是你写给我的诗\n[00:25.00]制作人 : 不是署名"
+	if got != want {
+		t.Errorf("Clean:\n got %q\nwant %q", got, want)
+	}
+	if credits.Lyricist != "方文山" || credits.Composer != "周杰伦" || credits.Producer != "" {
+		t.Errorf("credits = %+v", credits)
+	}
+}
+
+// 默认的广告规则只去掉整行声明
+func TestCleanAds(t *testing.T) {
+	var options CleanOptions
+	for _, pattern := range config.C.Cleanup.Ads {
+		options.Ads = append(options.Ads, regexp.MustCompile(pattern))
+	}
+	cases := []struct {
+		text    string
+		removed bool
+	}{
+		{"未经许可，不得翻唱或使用", true},
+		{"未经著作权人书面许可，任何人不得以任何方式使用", true},
+		{"未經許可 不得翻唱", true},
+		{"不得翻唱翻录", true},
+		{"著作权所有 翻唱必究", true},
+		{"版权所有 © 2024 某唱片", true},
+		{"TME享有本翻译作品的著作权", true},
+		{"未经你许可我已爱上你", false},
+		{"没有什么能不经你许可", false},
+		{"你说这份感情是你的版权所有 可我还是想要把它偷走", false},
+		{"这首歌不得不唱给你听", false},
+	}
+	for _, c := range cases {
+		got := Clean("[00:01.00]"+c.text, options, nil)
+		if removed := got == ""; removed != c.removed {
+			t.Errorf("%q: removed = %v, want %v", c.text, removed, c.removed)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package lyric
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// 假名占汉字和假名的比例达到这个值时认为是日语
+	kanaShare = 0.15
+	// 拼音 / 罗马字的词占全部词的比例达到这个值时认为是罗马音
+	romanizedShare = 0.85
+	// 常用词占全部词的比例达到这个值时认为是对应语言
+	stopwordShare = 0.1
+)
+
+var (
+	// 一个拼音音节, 可以带数字声调
+	pinyinSyllable = regexp.MustCompile(`^(?:zh|ch|sh|[bpmfdtnlgkhjqxrzcsyw])?(?:iang|iong|uang|ueng|ang|eng|ing|ong|uan|ian|iao|uai|ai|ei|ao|ou|an|en|in|un|ia|ie|iu|ua|uo|ue|ui|er|a|o|e|i|u|v)[1-5]?$`)
+	// 由平文式罗马字音节组成的词, 促音写作重复的辅音
+	romajiWord = regexp.MustCompile(`^(?:(?:kk|ss|tt|pp|cch|tch|ssh|sh|ch|ts|[kgnhmrbp]y|[kgsztdnhbpmyrwfjv])?[aeiou]|n)+$`)
+)
+
+// 拉丁字母语言的常用词
+var stopwords = map[string]map[string]bool{
+	"en": words("the you and i to a me my it is in of that your we be on for don't i'm can love all this"),
+	"es": words("el la de que y en los las un una por con mi tu no es yo te se lo del amor"),
+	"fr": words("le la les de des et je tu un une est que qui pas pour dans mon ton moi toi c'est"),
+	"de": words("der die das und ich du nicht ist ein eine zu mit mich dich mein dein wir auf es"),
+	"pt": words("o a os as de que e eu você um uma não com meu minha do da em pra"),
+	"it": words("il la di che e non un una per mi ti sono io tu con del della amore"),
+}
+
+func words(list string) map[string]bool {
+	result := map[string]bool{}
+	for _, w := range strings.Fields(list) {
+		result[w] = true
+	}
+	return result
+}
+
+// Detect 判断歌词的主要语言, 返回 BCP 47 标签: zh / ja / ko / ru / th 按文字判断, 拉丁字母按常用词判断 en / es / fr / de / pt / it;
+// 拉丁字母写的拼音和日语罗马字返回 zh-Latn / ja-Latn, 无法判断时返回空字符串.
+// 署名行和同一时间戳的后续行 (插在原文下面的翻译) 不参与判断
+func Detect(content string) string {
+	var han, kana, hangul, cyrillic, thai, latin int
+	var latinWords []string
+	for _, text := range detectTexts(content) {
+		for _, r := range text {
+			switch {
+			case unicode.Is(unicode.Han, r):
+				han++
+			case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+				kana++
+			case unicode.Is(unicode.Hangul, r):
+				hangul++
+			case unicode.Is(unicode.Cyrillic, r):
+				cyrillic++
+			case unicode.Is(unicode.Thai, r):
+				thai++
+			case unicode.Is(unicode.Latin, r):
+				latin++
+			}
+		}
+		latinWords = append(latinWords, strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.Is(unicode.Latin, r) && r != '\'' && r != '’' && !unicode.IsDigit(r)
+		})...)
+	}
+
+	// 拉丁字母和西里尔字母按 3 个字母一个音节与汉字比较
+	cjk := han + kana + hangul
+	switch {
+	case cjk == 0 && latin == 0 && cyrillic == 0 && thai == 0:
+		return ""
+	case cjk >= latin/3 && cjk >= cyrillic/3 && cjk >= thai:
+		switch {
+		case hangul >= han+kana:
+			return "ko"
+		case float64(kana) >= kanaShare*float64(han+kana):
+			return "ja"
+		}
+		return "zh"
+	case cyrillic >= latin && cyrillic/3 >= thai:
+		return "ru"
+	case thai > latin/3:
+		return "th"
+	}
+	return latinLanguage(latinWords)
+}
+
+// detectTexts 清理后每一行的文字, 纯文本歌词按行拆分
+func detectTexts(content string) []string {
+	content = Clean(content, CleanOptions{Credits: true, Blank: true}, nil)
+	parsed := Parse(content)
+	if len(parsed.Lines) == 0 {
+		var result []string
+		for _, line := range strings.Split(content, "\n") {
+			if !tagLine.MatchString(strings.TrimSpace(line)) {
+				result = append(result, line)
+			}
+		}
+		return result
+	}
+
+	var result []string
+	last := int64(-1)
+	for _, line := range parsed.Lines {
+		text := strings.TrimSpace(line.Text)
+		if text == "" || text == "//" || line.Start == last {
+			continue
+		}
+		last = line.Start
+		result = append(result, text)
+	}
+	return result
+}
+
+// latinLanguage 先判断是不是拼音 / 罗马字, 再按常用词比例最高的语言判断
+func latinLanguage(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	pinyin, romaji := 0, 0
+	counts := map[string]int{}
+	for _, w := range list {
+		plain := stripMarks(w)
+		if pinyinSyllable.MatchString(plain) {
+			pinyin++
+		}
+		if romajiWord.MatchString(plain) {
+			romaji++
+		}
+		w = strings.ReplaceAll(w, "’", "'")
+		for lang, set := range stopwords {
+			if set[w] {
+				counts[lang]++
+			}
+		}
+	}
+	total := float64(len(list))
+	switch {
+	case float64(pinyin) >= romanizedShare*total:
+		return "zh-Latn"
+	case float64(romaji) >= romanizedShare*total:
+		return "ja-Latn"
+	}
+
+	best := ""
+	for _, lang := range []string{"en", "es", "fr", "de", "pt", "it"} {
+		if counts[lang] > counts[best] {
+			best = lang
+		}
+	}
+	if best == "" || float64(counts[best]) < stopwordShare*total {
+		return ""
+	}
+	return best
+}
+
+// stripMarks 去掉声调符号等附加符号, ü 变为 u
+func stripMarks(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
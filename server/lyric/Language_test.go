@@ -0,0 +1,33 @@
+package lyric
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name, content, want string
+	}{
+		{"japanese", "[00:01.00]君の名前を呼んだ\n[00:05.00]夜空に星が降る", "ja"},
+		// 汉字为主但有假名时仍是日语
+		{"japanese mostly kanji", "[00:01.00]東京の空\n[00:05.00]雨が降る", "ja"},
+		{"chinese", "[00:01.00]故事的小黄花\n[00:05.00]从出生那年就飘着", "zh"},
+		{"korean", "[00:01.00]사랑해 너를\n[00:05.00]보고 싶어", "ko"},
+		{"russian", "[00:01.00]Я тебя люблю", "ru"},
+		// 拼音 (带或不带声调) 和日语罗马字不是英语
+		{"pinyin", "[00:01.00]gù shì de xiǎo huáng huā\n[00:05.00]cong chu sheng na nian jiu piao zhe", "zh-Latn"},
+		{"romaji", "[00:01.00]kimi no namae wo yonda\n[00:05.00]yozora ni hoshi ga furu", "ja-Latn"},
+		{"english", "[00:01.00]I will always love you\n[00:05.00]and I can see the light", "en"},
+		{"spanish", "[00:01.00]Te quiero con todo mi corazón\n[00:05.00]y no puedo vivir sin tu amor", "es"},
+		// 同一时间戳插在原文下面的翻译不参与判断
+		{"inline translation", "[00:01.00]君が好き\n[00:01.00]喜欢你\n[00:05.00]さよなら\n[00:05.00]再见", "ja"},
+		// 署名行不参与判断
+		{"credits", "[00:01.00]作词 : 方文山\n[00:02.00]I love you baby", "en"},
+		{"plain text", "君の名前を呼んだ\n夜空に星が降る", "ja"},
+		{"empty", "", ""},
+		{"no letters", "[00:01.00]12345 !!!", ""},
+	}
+	for _, c := range cases {
+		if got := Detect(c.content); got != c.want {
+			t.Errorf("%s: Detect = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package lyric
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 没有时长信息时最后一行的默认持续时间 (与客户端保持一致)
+const defaultLineDuration = 5000
+
+type Word struct {
+	Start    int64  `json:"start"`
+	Duration int64  `json:"duration"`
+	Text     string `json:"text"`
+}
+
+type Line struct {
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+	Text  string `json:"text"`
+	Words []Word `json:"words,omitempty"`
+}
+
+type Lyric struct {
+	Lines []Line            `json:"lines"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+var (
+	qrcContent = regexp.MustCompile(`(?s)LyricContent="([^"]*)"`)
+	// [start,duration] 开头的逐字歌词 (QRC / KRC / YRC)
+	timedLine = regexp.MustCompile(`^\[(\d+),(\d+)\](.*)$`)
+	lrcTime   = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	tagLine   = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]$`)
+	// KRC: <offset,duration,0>word, offset 相对行首
+	krcWord = regexp.MustCompile(`<(\d+),(\d+),\d+>([^<]*)`)
+	// YRC: (start,duration,0)word
+	yrcWord = regexp.MustCompile(`\((\d+),(\d+),\d+\)([^(]*)`)
+	// NetEase klyric: (0,duration)word, 依次累加
+	kWord = regexp.MustCompile(`\(0,(\d+)\)([^(]*)`)
+	// QRC: word(start,duration)
+	qrcWord = regexp.MustCompile(`([^(]*)\((\d+),(\d+)\)`)
+	// 增强 LRC: <mm:ss.xx>word
+	lrcWord = regexp.MustCompile(`<(\d+):(\d{1,2})(?:[.:](\d{1,3}))?>([^<]*)`)
+)
+
+// Decode 把接口里的 base64 歌词还原为文本, 不是 base64 时原样返回
+func Decode(content string) string {
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return content
+	}
+	return string(decoded)
+}
+
+// Parse 解析 LRC / 增强 LRC / QRC / KRC / YRC 文本, 返回按时间排序的行
+func Parse(content string) Lyric {
+	result := Lyric{Tags: map[string]string{}}
+	if m := qrcContent.FindStringSubmatch(content); len(m) > 1 {
+		content = html.UnescapeString(m[1])
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if m := timedLine.FindStringSubmatch(raw); m != nil {
+			result.Lines = append(result.Lines, parseTimedLine(atoi(m[1]), atoi(m[2]), m[3]))
+			continue
+		}
+		if strings.HasPrefix(raw, "{") {
+			if line, ok := parseJsonLine(raw); ok {
+				result.Lines = append(result.Lines, line)
+			}
+			continue
+		}
+		if lrcTime.MatchString(raw) {
+			result.Lines = append(result.Lines, parseLrcLine(raw)...)
+			continue
+		}
+		if m := tagLine.FindStringSubmatch(raw); m != nil {
+			result.Tags[strings.ToLower(m[1])] = strings.TrimSpace(m[2])
+		}
+	}
+
+	sort.SliceStable(result.Lines, func(i, j int) bool {
+		return result.Lines[i].Start < result.Lines[j].Start
+	})
+	for i := range result.Lines {
+		line := &result.Lines[i]
+		if line.End > line.Start {
+			continue
+		}
+		if i+1 < len(result.Lines) {
+			line.End = result.Lines[i+1].Start
+		} else {
+			line.End = line.Start + defaultLineDuration
+		}
+	}
+	return result
+}
+
+func parseTimedLine(start int64, duration int64, content string) Line {
+	line := Line{Start: start, End: start + duration}
+
+	if matches := krcWord.FindAllStringSubmatch(content, -1); matches != nil {
+		for _, m := range matches {
+			line.Words = append(line.Words, Word{Start: start + atoi(m[1]), Duration: atoi(m[2]), Text: html.UnescapeString(m[3])})
+		}
+	} else if matches := yrcWord.FindAllStringSubmatch(content, -1); matches != nil {
+		for _, m := range matches {
+			line.Words = append(line.Words, Word{Start: atoi(m[1]), Duration: atoi(m[2]), Text: html.UnescapeString(m[3])})
+		}
+	} else if strings.HasPrefix(content, "(0,") && kWord.MatchString(content) {
+		current := start
+		for _, m := range kWord.FindAllStringSubmatch(content, -1) {
+			word := Word{Start: current, Duration: atoi(m[1]), Text: html.UnescapeString(m[2])}
+			current += word.Duration
+			line.Words = append(line.Words, word)
+		}
+	} else if matches := qrcWord.FindAllStringSubmatch(content, -1); matches != nil {
+		for _, m := range matches {
+			line.Words = append(line.Words, Word{Start: atoi(m[2]), Duration: atoi(m[3]), Text: html.UnescapeString(m[1])})
+		}
+	} else {
+		line.Text = html.UnescapeString(content)
+	}
+
+	if len(line.Words) > 0 {
+		var text strings.Builder
+		for _, w := range line.Words {
+			text.WriteString(w.Text)
+		}
+		line.Text = text.String()
+	}
+	return line
+}
+
+// 一行可以有多个时间戳: [00:01.00][00:30.00]text
+func parseLrcLine(raw string) []Line {
+	var starts []int64
+	for {
+		m := lrcTime.FindStringSubmatch(raw)
+		if m == nil {
+			break
+		}
+		starts = append(starts, clock(m[1], m[2], m[3]))
+		raw = raw[len(m[0]):]
+	}
+
+	var words []Word
+	text := raw
+	if matches := lrcWord.FindAllStringSubmatch(raw, -1); matches != nil {
+		for i, m := range matches {
+			word := Word{Start: clock(m[1], m[2], m[3]), Text: html.UnescapeString(m[4])}
+			if i > 0 {
+				words[i-1].Duration = word.Start - words[i-1].Start
+			}
+			words = append(words, word)
+		}
+		// 最后一个时间标签通常只用来标记结束
+		if last := words[len(words)-1]; last.Text == "" && len(words) > 1 {
+			words = words[:len(words)-1]
+		}
+		var builder strings.Builder
+		builder.WriteString(raw[:strings.Index(raw, "<")])
+		for _, w := range words {
+			builder.WriteString(w.Text)
+		}
+		text = builder.String()
+	}
+
+	var lines []Line
+	for _, start := range starts {
+		line := Line{Start: start, Text: html.UnescapeString(strings.TrimSpace(text))}
+		if len(words) > 0 && len(starts) == 1 {
+			line.Words = words
+			if last := words[len(words)-1]; last.Duration > 0 {
+				line.End = last.Start + last.Duration
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// NetEase YRC 中的制作信息行: {"t":0,"c":[{"tx":"作词: "},{"tx":"xxx"}]}
+func parseJsonLine(raw string) (Line, bool) {
+	var data struct {
+		T int64 `json:"t"`
+		C []struct {
+			Tx string `json:"tx"`
+		} `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return Line{}, false
+	}
+	var text strings.Builder
+	for _, c := range data.C {
+		text.WriteString(c.Tx)
+	}
+	return Line{Start: data.T, Text: text.String()}, true
+}
+
+func clock(minutes string, seconds string, fraction string) int64 {
+	ms := atoi(minutes)*60000 + atoi(seconds)*1000
+	switch len(fraction) {
+	case 1:
+		ms += atoi(fraction) * 100
+	case 2:
+		ms += atoi(fraction) * 10
+	case 3:
+		ms += atoi(fraction)
+	}
+	return ms
+}
+
+func atoi(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,149 @@
+package lyric
+
+import (
+	_ "embed"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed profanity.txt
+var profanityList string
+
+// Masker 把屏蔽词的中间部分替换为 mask (f**k, 傻*), 字数不变, 逐字时间仍然对应原来的字
+type Masker struct {
+	mask rune
+	// 拉丁字母词整词匹配, prefixes 按词首匹配
+	words    map[string]bool
+	prefixes []string
+	// 其他文字在任意位置匹配, 长的在前
+	phrases []string
+}
+
+// DefaultProfanity 内置的各语言屏蔽词
+func DefaultProfanity() map[string][]string {
+	result := map[string][]string{}
+	for _, line := range strings.Split(profanityList, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		result[fields[0]] = append(result[fields[0]], fields[1:]...)
+	}
+	return result
+}
+
+// NewMasker 使用 lists 中 languages 对应的词, languages 为空时使用全部语言; mask 为空时使用 *
+func NewMasker(lists map[string][]string, languages []string, mask string) *Masker {
+	m := &Masker{mask: '*', words: map[string]bool{}}
+	if r, _ := utf8.DecodeRuneInString(mask); r != utf8.RuneError {
+		m.mask = r
+	}
+	for language, list := range lists {
+		if len(languages) > 0 && !slices.Contains(languages, language) {
+			continue
+		}
+		for _, word := range list {
+			word = strings.ToLower(strings.TrimSpace(word))
+			switch {
+			case word == "" || word == "*":
+			case !latinWord(strings.TrimSuffix(word, "*")):
+				m.phrases = append(m.phrases, word)
+			case strings.HasSuffix(word, "*"):
+				m.prefixes = append(m.prefixes, strings.TrimSuffix(word, "*"))
+			default:
+				m.words[word] = true
+			}
+		}
+	}
+	slices.SortFunc(m.phrases, func(a, b string) int {
+		return utf8.RuneCountInString(b) - utf8.RuneCountInString(a)
+	})
+	return m
+}
+
+// Mask 只处理歌词文字, 时间戳 / 标签 / 逐字时间保持不变
+func (m *Masker) Mask(content string) string {
+	return Transform(content, m.Text)
+}
+
+// Text 屏蔽一段文字
+func (m *Masker) Text(text string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	// 大小写转换改变了字数时不处理 (极少数字符)
+	if len(lower) != len(runes) {
+		return text
+	}
+	masked := make([]bool, len(runes))
+
+	for i := 0; i < len(lower); {
+		if !wordRune(lower[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(lower) && wordRune(lower[j]) {
+			j++
+		}
+		if n := m.matchWord(string(lower[i:j])); n > 0 {
+			m.cover(runes, masked, i, i+n)
+		}
+		i = j
+	}
+
+	for _, phrase := range m.phrases {
+		p := []rune(phrase)
+		for i := 0; i+len(p) <= len(lower); i++ {
+			if !masked[i] && slices.Equal(lower[i:i+len(p)], p) {
+				m.cover(runes, masked, i, i+len(p))
+				i += len(p) - 1
+			}
+		}
+	}
+	return string(runes)
+}
+
+// matchWord 返回词首需要屏蔽的字数, 整词匹配时为整个词
+func (m *Masker) matchWord(word string) int {
+	if m.words[word] {
+		return utf8.RuneCountInString(word)
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return utf8.RuneCountInString(prefix)
+		}
+	}
+	return 0
+}
+
+// cover 保留 [from, to) 的第一个字和最后一个字 (两个字时只保留第一个, 一个字时全部替换), 中间替换为 mask
+func (m *Masker) cover(runes []rune, masked []bool, from int, to int) {
+	first, last := from, to-1
+	switch to - from {
+	case 1:
+		first, last = -1, -1
+	case 2:
+		last = -1
+	}
+	for i := from; i < to; i++ {
+		masked[i] = true
+		if i != first && i != last {
+			runes[i] = m.mask
+		}
+	}
+}
+
+func latinWord(word string) bool {
+	for _, r := range word {
+		if !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return word != ""
+}
+
+func wordRune(r rune) bool {
+	return unicode.Is(unicode.Latin, r) || unicode.IsDigit(r)
+}
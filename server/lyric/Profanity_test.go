@@ -0,0 +1,39 @@
+package lyric
+
+import "testing"
+
+func TestMasker(t *testing.T) {
+	masker := NewMasker(DefaultProfanity(), nil, "*")
+	cases := []struct {
+		name, content, want string
+	}{
+		// 不区分大小写, 保留原来的大小写
+		{"case", "FUCK you", "F**K you"},
+		{"whole word", "shit", "s**t"},
+		{"listed form", "shitty", "s****y"},
+		// 以 * 结尾的词按词首匹配, 只屏蔽匹配的部分
+		{"prefix", "Fucking hell", "F**king hell"},
+		{"long prefix", "motherfuckers", "m********kers"},
+		// 拉丁字母词不匹配词中间的部分
+		{"inside words", "Scunthorpe class", "Scunthorpe class"},
+		{"cjk phrases", "他妈的 傻逼", "他*的 傻*"},
+		{"two characters", "操你", "操*"},
+		{"japanese", "クソったれ", "ク*ったれ"},
+		// 시발점 (起点) 不是脏话
+		{"korean", "시발점에서 씨발", "시발점에서 씨*"},
+		// 时间戳和逐字时间不变, 跨多个逐字片段的词也会屏蔽
+		{"lrc word timing", "[00:01.00]<00:01.00>fuck <00:01.50>you\n[00:03.00]傻逼", "[00:01.00]<00:01.00>f**k <00:01.50>you\n[00:03.00]傻*"},
+		{"qrc word timing", "[00:01.000]fucking[1000,500] you[1500,300]", "[00:01.000]f**king[1000,500] you[1500,300]"},
+		{"split word", "[1000,800]s(1000,400)h(1400,100)it(1500,300)", "[1000,800]s(1000,400)*(1400,100)*t(1500,300)"},
+	}
+	for _, c := range cases {
+		if got := masker.Mask(c.content); got != c.want {
+			t.Errorf("%s: Mask(%q) = %q, want %q", c.name, c.content, got, c.want)
+		}
+	}
+
+	// 只使用指定语言的词表和 mask
+	if got := NewMasker(DefaultProfanity(), []string{"en"}, "#").Text("傻逼 SHIT"); got != "傻逼 S##T" {
+		t.Errorf("languages: got %q", got)
+	}
+}
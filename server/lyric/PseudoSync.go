@@ -0,0 +1,149 @@
+package lyric
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// 没有参考时间轴时, 前奏和尾奏各按时长的这个比例估计, 限制在 [minIntro, maxIntro] (ms)
+	introShare = 0.08
+	minIntro   = 3000
+	maxIntro   = 20000
+	// 每行额外的换气时间和段落之间的间隔, 按音节数计
+	breathWeight = 2
+	stanzaWeight = 6
+)
+
+// Plain 有文字但没有任何时间戳的歌词
+func Plain(content string) bool {
+	return strings.TrimSpace(content) != "" && len(Parse(content).Lines) == 0
+}
+
+// PseudoSync 为纯文本歌词估计每行的时间, duration 为歌曲时长 (ms).
+// reference 是同一首歌的另一份逐行歌词 (文字可以不同): 有字的行数相同时直接使用它每一行的时间, 否则只取它的首尾作为歌词的范围;
+// 没有参考时去掉估计的前奏和尾奏. 范围内按每行的音节数 (加换气时间) 分配, 空行分开的段落之间额外留出间隔并插入空行标记上一行结束
+func PseudoSync(content string, duration int64, reference Lyric) (Lyric, bool) {
+	type plainLine struct {
+		text   string
+		stanza bool
+	}
+	var lines []plainLine
+	var credits []string
+	stanza := false
+	for _, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "":
+			stanza = len(lines) > 0
+		case tagLine.MatchString(text):
+		case len(lines) == 0 && isCredit(text):
+			// 开头的署名行放在 0 秒, 不占用演唱时间
+			credits = append(credits, text)
+		default:
+			lines = append(lines, plainLine{text: text, stanza: stanza})
+			stanza = false
+		}
+	}
+	if len(lines) == 0 {
+		return Lyric{}, false
+	}
+
+	result := Lyric{}
+	for _, text := range credits {
+		result.Lines = append(result.Lines, Line{Start: 0, End: 0, Text: text})
+	}
+
+	var timed []Line
+	for _, line := range reference.Lines {
+		if strings.TrimSpace(line.Text) == "" || isCredit(line.Text) {
+			continue
+		}
+		// NetEase 的翻译与原文同一时间戳
+		if n := len(timed); n > 0 && timed[n-1].Start == line.Start {
+			continue
+		}
+		timed = append(timed, line)
+	}
+	if len(timed) == len(lines) {
+		for i, line := range lines {
+			result.Lines = append(result.Lines, Line{Start: timed[i].Start, End: timed[i].End, Text: line.text})
+		}
+		return result, true
+	}
+
+	var start, end int64
+	switch {
+	case len(timed) > 0:
+		start, end = timed[0].Start, timed[len(timed)-1].End
+		if duration > 0 {
+			end = min(end, duration)
+		}
+	case duration > 0:
+		intro := min(max(int64(float64(duration)*introShare), minIntro), maxIntro)
+		start, end = intro, duration-intro
+		if end-start < duration/2 {
+			start, end = 0, duration
+		}
+	default:
+		return Lyric{}, false
+	}
+	if end <= start {
+		return Lyric{}, false
+	}
+
+	weights := make([]float64, len(lines))
+	total := 0.0
+	for i, line := range lines {
+		if line.stanza {
+			total += stanzaWeight
+		}
+		weights[i] = breathWeight + syllables(line.text)
+		total += weights[i]
+	}
+	scale := float64(end-start) / total
+	position := 0.0
+	for i, line := range lines {
+		if line.stanza {
+			position += stanzaWeight
+		}
+		lineStart := start + int64(position*scale)
+		position += weights[i]
+		lineEnd := start + int64(position*scale)
+		result.Lines = append(result.Lines, Line{Start: lineStart, End: lineEnd, Text: line.text})
+		if i+1 == len(lines) || lines[i+1].stanza {
+			result.Lines = append(result.Lines, Line{Start: lineEnd, End: lineEnd})
+		}
+	}
+	return result, true
+}
+
+// syllables 粗略估计一行的音节数: 汉字 / 假名 / 谚文每字一个, 其他文字每个词按 3 个字母一个音节
+func syllables(text string) float64 {
+	count, letters := 0.0, 0
+	flush := func() {
+		if letters > 0 {
+			count += math.Max(1, float64(letters)/3)
+			letters = 0
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			count++
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '\'':
+			letters++
+		default:
+			flush()
+		}
+	}
+	flush()
+	return count
+}
+
+func isCredit(text string) bool {
+	_, _, ok := credit(text)
+	return ok
+}
@@ -0,0 +1,79 @@
+package lyric
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPseudoSync(t *testing.T) {
+	plain := "作词 : 方文山\n故事的小黄花\n从出生那年就飘着\n\n童年的荡秋千"
+	cases := []struct {
+		name      string
+		duration  int64
+		reference string
+		want      []Line
+		ok        bool
+	}{
+		{
+			// 行数相同时直接使用参考的每一行时间, 署名放在 0 秒
+			name:      "same lines",
+			duration:  200000,
+			reference: "[00:10.00]故事的小黄花\n[00:15.00]从出生那年就飘着\n[00:20.00]童年的荡秋千\n[00:25.00]",
+			want: []Line{
+				{Text: "作词 : 方文山"},
+				{Start: 10000, End: 15000, Text: "故事的小黄花"},
+				{Start: 15000, End: 20000, Text: "从出生那年就飘着"},
+				{Start: 20000, End: 25000, Text: "童年的荡秋千"},
+			},
+			ok: true,
+		},
+		{
+			// 行数不同时只取参考的首尾, 按音节数加换气时间分配 (6+2, 8+2, 段落间隔 6, 6+2), 段落结束时插入空行
+			name:      "reference bounds",
+			duration:  200000,
+			reference: "[00:10.00]故事的小黄花\n[00:40.00]童年的荡秋千\n[00:50.00]",
+			want: []Line{
+				{Text: "作词 : 方文山"},
+				{Start: 10000, End: 20000, Text: "故事的小黄花"},
+				{Start: 20000, End: 32500, Text: "从出生那年就飘着"},
+				{Start: 32500, End: 32500},
+				{Start: 40000, End: 50000, Text: "童年的荡秋千"},
+				{Start: 50000, End: 50000},
+			},
+			ok: true,
+		},
+		{
+			// 没有参考时去掉时长 8% 的前奏和尾奏
+			name:     "duration",
+			duration: 100000,
+			want: []Line{
+				{Text: "作词 : 方文山"},
+				{Start: 8000, End: 29000, Text: "故事的小黄花"},
+				{Start: 29000, End: 55250, Text: "从出生那年就飘着"},
+				{Start: 55250, End: 55250},
+				{Start: 71000, End: 92000, Text: "童年的荡秋千"},
+				{Start: 92000, End: 92000},
+			},
+			ok: true,
+		},
+		{name: "no duration", ok: false},
+	}
+	for _, c := range cases {
+		got, ok := PseudoSync(plain, c.duration, Parse(c.reference))
+		if ok != c.ok || !reflect.DeepEqual(got.Lines, c.want) {
+			t.Errorf("%s: PseudoSync = %+v, %v\nwant %+v, %v", c.name, got.Lines, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	for content, want := range map[string]bool{
+		"故事的小黄花\n从出生那年就飘着": true,
+		"[00:01.00]故事的小黄花": false,
+		" \n ":             false,
+	} {
+		if got := Plain(content); got != want {
+			t.Errorf("Plain(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
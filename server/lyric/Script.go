@@ -0,0 +1,85 @@
+package lyric
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 时间戳 / 标签 / 逐字时间: [00:01.00] [ti:xx] [1000,500] <00:01.00> <0,500,0> (1000,500) (1000,500,0)
+var markup = regexp.MustCompile(`\[[^\]]*\]|<[^>]*>|\(\d+,\d+(?:,-?\d+)?\)`)
+
+// Transform 只转换歌词文字, 时间戳 / 标签 / 逐字时间保持不变
+func Transform(content string, convert func(string) string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = transformLine(line, convert)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TransformPieces 把一行中的逐字片段一起交给 convert, convert 返回同样数量的片段, 用于需要整行语境的转换
+func TransformPieces(content string, convert func(texts []string) []string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "{") {
+			continue
+		}
+		texts, tags := splitMarkup(line)
+		if strings.TrimSpace(strings.Join(texts, "")) == "" {
+			continue
+		}
+		lines[i] = joinMarkup(convert(texts), tags)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// transformLine 整行一起转换以保留词组语境, 字数不变时再按原来的位置拆回各个逐字片段
+func transformLine(line string, convert func(string) string) string {
+	// NetEase 的 JSON 署名行, 键都是 ASCII 不受影响
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return convert(line)
+	}
+
+	texts, tags := splitMarkup(line)
+	joined := strings.Join(texts, "")
+	if strings.TrimSpace(joined) == "" {
+		return line
+	}
+	converted := convert(joined)
+	if utf8.RuneCountInString(converted) == utf8.RuneCountInString(joined) {
+		runes := []rune(converted)
+		for i, text := range texts {
+			n := utf8.RuneCountInString(text)
+			texts[i] = string(runes[:n])
+			runes = runes[n:]
+		}
+	} else {
+		for i, text := range texts {
+			texts[i] = convert(text)
+		}
+	}
+	return joinMarkup(texts, tags)
+}
+
+// splitMarkup 拆成文字片段和标签, texts 比 tags 多一个
+func splitMarkup(line string) (texts []string, tags []string) {
+	last := 0
+	for _, loc := range markup.FindAllStringIndex(line, -1) {
+		texts = append(texts, line[last:loc[0]])
+		tags = append(tags, line[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	return append(texts, line[last:]), tags
+}
+
+func joinMarkup(texts []string, tags []string) string {
+	var b strings.Builder
+	for i, text := range texts {
+		b.WriteString(text)
+		if i < len(tags) {
+			b.WriteString(tags[i])
+		}
+	}
+	return b.String()
+}
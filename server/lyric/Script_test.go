@@ -0,0 +1,47 @@
+package lyric
+
+import (
+	apputils "lyrics/app-utils"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	cases := []struct {
+		name    string
+		script  string
+		content string
+		// 转换后把 & 展开, 模拟字数变化的转换
+		expand bool
+		want   string
+	}{
+		// 字数不变时整行转换, 再按原来的位置拆回逐字片段, "发" 按词组转成 "髮"
+		{"t words", "t", "[1000,600]<0,300,0>头<300,300,0>发", false, "[1000,600]<0,300,0>頭<300,300,0>髮"},
+		{"tw words", "tw", "[1000,600](1000,300,0)里(1300,300,0)面", false, "[1000,600](1000,300,0)裡(1300,300,0)面"},
+		{"hk words", "hk", "[1000,600](1000,300,0)里(1300,300,0)面", false, "[1000,600](1000,300,0)裏(1300,300,0)面"},
+		{"s words", "s", "[00:01.00]<00:01.00>頭<00:01.30>髮", false, "[00:01.00]<00:01.00>头<00:01.30>发"},
+		// 标签和时间戳不变
+		{"t tags", "t", "[ti:理发]\n[00:01.00]理发", false, "[ti:理发]\n[00:01.00]理髮"},
+		{"json credits", "t", `{"t":0,"c":[{"tx":"作词: "},{"tx":"头发"}]}`, false, `{"t":0,"c":[{"tx":"作詞: "},{"tx":"頭髮"}]}`},
+		// 字数变化时不能按位置拆回, 每个片段单独转换, 失去词组语境
+		{"t count changes", "t", "[1000,900]<0,300,0>头<300,300,0>发<600,300,0>&", true, "[1000,900]<0,300,0>頭<300,300,0>發<600,300,0> and "},
+		{"tw count changes", "tw", "[1000,600](1000,300,0)里面(1300,300,0)&", true, "[1000,600](1000,300,0)裡面(1300,300,0) and "},
+		{"hk count changes", "hk", "[1000,600](1000,300,0)头发(1300,300,0)&", true, "[1000,600](1000,300,0)頭髮(1300,300,0) and "},
+		{"s count changes", "s", "[00:01.00]<00:01.00>頭<00:01.30>髮&", true, "[00:01.00]<00:01.00>头<00:01.30>发 and "},
+	}
+	for _, c := range cases {
+		convert := func(text string) string {
+			converted, err := apputils.ConvertScript(text, c.script)
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if c.expand {
+				converted = strings.ReplaceAll(converted, "&", " and ")
+			}
+			return converted
+		}
+		if got := Transform(c.content, convert); got != c.want {
+			t.Errorf("%s: Transform = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
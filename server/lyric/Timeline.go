@@ -0,0 +1,119 @@
+package lyric
+
+import "sort"
+
+// Position 某一时刻在歌词中的位置
+type Position struct {
+	// 当前行, -1 表示还没到第一行
+	Line int `json:"line"`
+	// 当前字, -1 表示没有逐字信息或还没到第一个字
+	Word int `json:"word"`
+	// 当前行是否仍在持续 (false 表示处于两行之间的间隙)
+	Active bool `json:"active"`
+	// 当前字的进度 0~1
+	Progress float64 `json:"progress"`
+}
+
+// Locate 计算 ms 时刻所在的行和字
+func (l Lyric) Locate(ms int64) Position {
+	position := Position{Line: -1, Word: -1}
+	index := sort.Search(len(l.Lines), func(i int) bool {
+		return l.Lines[i].Start > ms
+	}) - 1
+	if index < 0 {
+		return position
+	}
+	line := l.Lines[index]
+	position.Line = index
+	position.Active = ms < line.End
+
+	word := sort.Search(len(line.Words), func(i int) bool {
+		return line.Words[i].Start > ms
+	}) - 1
+	if word < 0 {
+		return position
+	}
+	position.Word = word
+	if w := line.Words[word]; w.Duration > 0 {
+		position.Progress = min(1, float64(ms-w.Start)/float64(w.Duration))
+	} else {
+		position.Progress = 1
+	}
+	return position
+}
+
+// NextChange 返回 ms 之后行或字第一次发生变化的时间, 之后不再变化时返回 -1
+func (l Lyric) NextChange(ms int64) int64 {
+	next := int64(-1)
+	earlier := func(t int64) {
+		if t > ms && (next < 0 || t < next) {
+			next = t
+		}
+	}
+
+	position := l.Locate(ms)
+	if position.Line+1 < len(l.Lines) {
+		earlier(l.Lines[position.Line+1].Start)
+	}
+	if position.Line >= 0 {
+		line := l.Lines[position.Line]
+		earlier(line.End)
+		if position.Word+1 < len(line.Words) {
+			earlier(line.Words[position.Word+1].Start)
+		}
+	}
+	return next
+}
+
+type IndexedLine struct {
+	Index int `json:"index"`
+	Line
+}
+
+type ActiveWord struct {
+	Index int `json:"index"`
+	Word
+	Progress float64 `json:"progress"`
+}
+
+// Snapshot 某一时刻的歌词状态, 供无状态的展示端直接使用
+type Snapshot struct {
+	Position int64         `json:"position"`
+	Active   bool          `json:"active"`
+	Line     *IndexedLine  `json:"line"`
+	Word     *ActiveWord   `json:"word"`
+	Previous []IndexedLine `json:"previous"`
+	Next     []IndexedLine `json:"next"`
+	// 距离下一行开始的时间, 没有下一行时为 -1
+	UntilNext int64 `json:"until_next"`
+}
+
+// At 计算 ms 时刻的当前行 / 字, 以及前后各 context 行
+func (l Lyric) At(ms int64, context int) Snapshot {
+	position := l.Locate(ms)
+	snapshot := Snapshot{
+		Position:  ms,
+		Active:    position.Active,
+		Previous:  []IndexedLine{},
+		Next:      []IndexedLine{},
+		UntilNext: -1,
+	}
+
+	if position.Line >= 0 {
+		line := l.Lines[position.Line]
+		snapshot.Line = &IndexedLine{Index: position.Line, Line: line}
+		if position.Word >= 0 {
+			snapshot.Word = &ActiveWord{Index: position.Word, Word: line.Words[position.Word], Progress: position.Progress}
+		}
+	}
+	for i := max(0, position.Line-context); i < position.Line; i++ {
+		snapshot.Previous = append(snapshot.Previous, IndexedLine{Index: i, Line: l.Lines[i]})
+	}
+	for i := position.Line + 1; i < len(l.Lines) && i <= position.Line+context; i++ {
+		snapshot.Next = append(snapshot.Next, IndexedLine{Index: i, Line: l.Lines[i]})
+	}
+	if next := position.Line + 1; next < len(l.Lines) {
+		snapshot.UntilNext = l.Lines[next].Start - ms
+	}
+	return snapshot
+}
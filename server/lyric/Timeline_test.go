@@ -0,0 +1,158 @@
+package lyric
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// 第一行逐字且有 0 时长的字, 第二行没有逐字, 第三行第一个字晚于行首
+var timelineFixture = Lyric{Lines: []Line{
+	{Start: 1000, End: 3000, Text: "abc", Words: []Word{
+		{Start: 1000, Duration: 500, Text: "a"},
+		{Start: 1500, Duration: 0, Text: "b"},
+		{Start: 2000, Duration: 1000, Text: "c"},
+	}},
+	{Start: 4000, End: 6000, Text: "plain"},
+	{Start: 6000, End: 8000, Text: "x", Words: []Word{
+		{Start: 6500, Duration: 500, Text: "x"},
+	}},
+}}
+
+func TestLocate(t *testing.T) {
+	cases := []struct {
+		name string
+		ms   int64
+		want Position
+	}{
+		{"before first line", 999, Position{Line: -1, Word: -1}},
+		{"negative", -500, Position{Line: -1, Word: -1}},
+		{"line start", 1000, Position{Line: 0, Word: 0, Active: true, Progress: 0}},
+		{"inside word", 1250, Position{Line: 0, Word: 0, Active: true, Progress: 0.5}},
+		{"zero duration word", 1500, Position{Line: 0, Word: 1, Active: true, Progress: 1}},
+		{"last ms of line", 2999, Position{Line: 0, Word: 2, Active: true, Progress: 0.999}},
+		// End 不属于当前行, 进入间隙后保留最后一个字
+		{"line end", 3000, Position{Line: 0, Word: 2, Active: false, Progress: 1}},
+		{"gap", 3500, Position{Line: 0, Word: 2, Active: false, Progress: 1}},
+		{"line without words", 4000, Position{Line: 1, Word: -1, Active: true}},
+		// 上一行的 End 与下一行的 Start 相同时切到下一行
+		{"adjacent line", 6000, Position{Line: 2, Word: -1, Active: true}},
+		{"first word", 6500, Position{Line: 2, Word: 0, Active: true, Progress: 0}},
+		{"after last line", 8000, Position{Line: 2, Word: 0, Active: false, Progress: 1}},
+		{"long after", 100000, Position{Line: 2, Word: 0, Active: false, Progress: 1}},
+	}
+	for _, c := range cases {
+		got := timelineFixture.Locate(c.ms)
+		if got.Line != c.want.Line || got.Word != c.want.Word || got.Active != c.want.Active || math.Abs(got.Progress-c.want.Progress) > 1e-9 {
+			t.Errorf("%s: Locate(%d) = %+v, want %+v", c.name, c.ms, got, c.want)
+		}
+	}
+
+	if got := (Lyric{}).Locate(1000); got != (Position{Line: -1, Word: -1}) {
+		t.Errorf("empty: Locate = %+v", got)
+	}
+}
+
+func TestNextChange(t *testing.T) {
+	cases := []struct {
+		ms   int64
+		want int64
+	}{
+		{0, 1000},
+		{999, 1000},
+		{1000, 1500},
+		{1500, 2000},
+		{2000, 3000},
+		{2999, 3000},
+		{3000, 4000},
+		// 行尾和下一行开始相同, 只变化一次
+		{4000, 6000},
+		{6000, 6500},
+		{6500, 8000},
+		{8000, -1},
+		{100000, -1},
+	}
+	for _, c := range cases {
+		if got := timelineFixture.NextChange(c.ms); got != c.want {
+			t.Errorf("NextChange(%d) = %d, want %d", c.ms, got, c.want)
+		}
+	}
+
+	if got := (Lyric{}).NextChange(0); got != -1 {
+		t.Errorf("empty: NextChange = %d", got)
+	}
+}
+
+func TestAt(t *testing.T) {
+	lines := make([]Line, 5)
+	for i := range lines {
+		lines[i] = Line{Start: int64(i+1) * 1000, End: int64(i+1)*1000 + 800, Text: string(rune('a' + i))}
+	}
+	l := Lyric{Lines: lines}
+	cases := []struct {
+		name      string
+		ms        int64
+		context   int
+		line      int
+		active    bool
+		previous  []int
+		next      []int
+		untilNext int64
+	}{
+		// 第一行之前没有当前行, Next 从第一行开始
+		{"before first line", 500, 2, -1, false, nil, []int{0, 1}, 500},
+		{"first line", 1000, 2, 0, true, nil, []int{1, 2}, 1000},
+		{"middle", 3200, 2, 2, true, []int{0, 1}, []int{3, 4}, 800},
+		{"gap", 3900, 1, 2, false, []int{1}, []int{3}, 100},
+		// context 超出范围时只返回存在的行
+		{"context beyond bounds", 2000, 10, 1, true, []int{0}, []int{2, 3, 4}, 1000},
+		{"zero context", 3000, 0, 2, true, nil, nil, 1000},
+		{"last line", 5000, 2, 4, true, []int{2, 3}, nil, -1},
+		// 最后一行结束后仍停在最后一行, 没有下一行
+		{"after last line", 9000, 2, 4, false, []int{2, 3}, nil, -1},
+	}
+	indexes := func(lines []IndexedLine) []int {
+		var result []int
+		for _, line := range lines {
+			if line.Text != l.Lines[line.Index].Text {
+				t.Errorf("line %d text = %q", line.Index, line.Text)
+			}
+			result = append(result, line.Index)
+		}
+		return result
+	}
+	for _, c := range cases {
+		snapshot := l.At(c.ms, c.context)
+		line := -1
+		if snapshot.Line != nil {
+			line = snapshot.Line.Index
+		}
+		if line != c.line || snapshot.Active != c.active || snapshot.Position != c.ms || snapshot.UntilNext != c.untilNext {
+			t.Errorf("%s: line %d active %v position %d until_next %d, want %d %v %d %d", c.name, line, snapshot.Active, snapshot.Position, snapshot.UntilNext, c.line, c.active, c.ms, c.untilNext)
+		}
+		if got := indexes(snapshot.Previous); !reflect.DeepEqual(got, c.previous) {
+			t.Errorf("%s: previous = %v, want %v", c.name, got, c.previous)
+		}
+		if got := indexes(snapshot.Next); !reflect.DeepEqual(got, c.next) {
+			t.Errorf("%s: next = %v, want %v", c.name, got, c.next)
+		}
+		// 空列表序列化为 [] 而不是 null
+		if snapshot.Previous == nil || snapshot.Next == nil {
+			t.Errorf("%s: nil previous / next", c.name)
+		}
+	}
+}
+
+func TestAtWord(t *testing.T) {
+	snapshot := timelineFixture.At(1250, 1)
+	if snapshot.Word == nil || snapshot.Word.Index != 0 || snapshot.Word.Text != "a" || snapshot.Word.Progress != 0.5 {
+		t.Errorf("word = %+v", snapshot.Word)
+	}
+	// 行首还没到第一个字
+	if snapshot := timelineFixture.At(6000, 1); snapshot.Word != nil || snapshot.Line == nil || snapshot.Line.Index != 2 {
+		t.Errorf("before first word: line %+v word %+v", snapshot.Line, snapshot.Word)
+	}
+	if snapshot := timelineFixture.At(0, 1); snapshot.Line != nil || snapshot.Word != nil {
+		t.Errorf("before first line: line %+v word %+v", snapshot.Line, snapshot.Word)
+	}
+}
@@ -0,0 +1,87 @@
+package lyric
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format 输出为 LRC, 有逐字时间时使用增强 LRC 的 <mm:ss.xx> 标签
+func Format(l Lyric) string {
+	var builder strings.Builder
+	keys := make([]string, 0, len(l.Tags))
+	for k := range l.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		builder.WriteString(fmt.Sprintf("[%s:%s]\n", k, l.Tags[k]))
+	}
+
+	for _, line := range l.Lines {
+		builder.WriteString(timestamp("[%s]", line.Start))
+		if len(line.Words) == 0 {
+			builder.WriteString(line.Text)
+		} else {
+			for _, w := range line.Words {
+				builder.WriteString(timestamp("<%s>", w.Start))
+				builder.WriteString(w.Text)
+			}
+			last := line.Words[len(line.Words)-1]
+			builder.WriteString(timestamp("<%s>", last.Start+last.Duration))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func timestamp(format string, ms int64) string {
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf(format, fmt.Sprintf("%02d:%02d.%02d", ms/60000, ms/1000%60, ms%1000/10))
+}
+
+// Align 把 other 的每一行对齐到 main 中开始时间最接近的行, 误差超过 tolerance 的保留原时间
+func Align(main Lyric, other Lyric, tolerance int64) Lyric {
+	result := Lyric{Tags: other.Tags}
+	for _, line := range other.Lines {
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		if i, ok := main.Nearest(line.Start, tolerance); ok {
+			shift := main.Lines[i].Start - line.Start
+			line.Start += shift
+			line.End = main.Lines[i].End
+			for w := range line.Words {
+				line.Words[w].Start += shift
+			}
+		}
+		result.Lines = append(result.Lines, line)
+	}
+	sort.SliceStable(result.Lines, func(i, j int) bool {
+		return result.Lines[i].Start < result.Lines[j].Start
+	})
+	return result
+}
+
+// Nearest 返回开始时间与 ms 最接近且误差不超过 tolerance 的行
+func (l Lyric) Nearest(ms int64, tolerance int64) (int, bool) {
+	i := sort.Search(len(l.Lines), func(i int) bool {
+		return l.Lines[i].Start >= ms
+	})
+	best, diff := -1, tolerance+1
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(l.Lines) {
+			continue
+		}
+		d := l.Lines[j].Start - ms
+		if d < 0 {
+			d = -d
+		}
+		if d < diff {
+			best, diff = j, d
+		}
+	}
+	return best, best >= 0
+}
@@ -0,0 +1,16 @@
+package model
+
+// LyricCredits 歌词的署名信息
+type LyricCredits struct {
+	// 歌词上传者 (NetEase lyricUser / LRC 的 [by:])
+	Uploader string `json:"uploader,omitempty"`
+	// 翻译者 (NetEase transUser)
+	Translator string `json:"translator,omitempty"`
+	// 以下从歌词开头的署名行中提取
+	Lyricist string `json:"lyricist,omitempty"`
+	Composer string `json:"composer,omitempty"`
+	Arranger string `json:"arranger,omitempty"`
+	Producer string `json:"producer,omitempty"`
+	// 其他署名, 键为原文中的标签 (和声 / 混音 / 出品 ...)
+	Other map[string]string `json:"other,omitempty"`
+}
@@ -0,0 +1,7 @@
+package model
+
+// LyricSource 去重后合并到同一条结果中的来源
+type LyricSource struct {
+	Type string `json:"type"`
+	Lid  string `json:"lid"`
+}
@@ -13,6 +13,20 @@ type LRCLIBResponse struct {
 	SyncedLyrics string  `json:"syncedLyrics"`
 }
 
+type LRCLIBChallenge struct {
+	Prefix string `json:"prefix"`
+	Target string `json:"target"`
+}
+
+type LRCLIBPublish struct {
+	TrackName    string  `json:"trackName"`
+	ArtistName   string  `json:"artistName"`
+	AlbumName    string  `json:"albumName"`
+	Duration     float64 `json:"duration"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+}
+
 // Kugou
 type KugouLKSearchResponse struct {
 	Status  int    `json:"status"`
@@ -68,24 +82,27 @@ type NetEaseLKSearchResponse struct {
 }
 
 type NetEaseLKSingleLyricsResponse struct {
-	Lrc struct {
-		Lyric string `json:"lyric"`
-	} `json:"lrc"`
-	KLyric struct {
-		Lyric string `json:"lyric"`
-	} `json:"klyric"`
-	TLyric struct {
-		Lyric string `json:"lyric"`
-	} `json:"tlyric"`
-	Yrc struct {
-		Lyric string `json:"lyric"`
-	} `json:"yrc"`
+	Lrc       NetEaseLyricsBlock `json:"lrc"`
+	KLyric    NetEaseLyricsBlock `json:"klyric"`
+	TLyric    NetEaseLyricsBlock `json:"tlyric"`
+	RomaLrc   NetEaseLyricsBlock `json:"romalrc"`
+	Yrc       NetEaseLyricsBlock `json:"yrc"`
+	YtLrc     NetEaseLyricsBlock `json:"ytlrc"`
+	YRomaLrc  NetEaseLyricsBlock `json:"yromalrc"`
 	LyricUser struct {
 		Nickname string `json:"nickname"`
 	} `json:"lyricUser"`
+	TransUser struct {
+		Nickname string `json:"nickname"`
+	} `json:"transUser"`
 	Code int `json:"code"`
 }
 
+type NetEaseLyricsBlock struct {
+	Version int    `json:"version"`
+	Lyric   string `json:"lyric"`
+}
+
 // QQMusic
 type QQMusicLKSearchResponse struct {
 	Code int `json:"code"`
@@ -101,6 +118,15 @@ type QQMusicLKSearchResponse struct {
 	} `json:"data"`
 }
 
+type QQMusicSongDetailResponse struct {
+	Code int `json:"code"`
+	Data []struct {
+		ID   int64  `json:"id"`
+		Mid  string `json:"mid"`
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
 type QQMusicLKSingleLyricsResponse struct {
 	RetCode int    `json:"retcode"`
 	Code    int    `json:"code"`
@@ -0,0 +1,15 @@
+package model
+
+const (
+	// 纯音乐, 所有 Provider 都没有歌词或只返回了纯音乐的占位文字
+	StatusInstrumental = "instrumental"
+	// 搜索过但没有找到歌词
+	StatusNotFound = "not_found"
+)
+
+// LyricsStatus 没有歌词时保存的结果, RetryAt 之前不再请求上游
+type LyricsStatus struct {
+	Sid     string `json:"sid"`
+	Status  string `json:"status"`
+	RetryAt string `json:"retry_at"`
+}
@@ -7,8 +7,27 @@ type MusicRelation struct {
 	Lid    string `json:"lid"`
 	Lyrics string `json:"lyrics"`
 	Trans  string `json:"trans"`
+	// 罗马音, 与 Trans 一样为 base64
+	Roma   string `json:"roma,omitempty"`
 	Type   string `json:"type"`
 	Offset int64  `json:"offset"`
+	// 署名信息
+	Credits *LyricCredits `json:"credits,omitempty"`
+	// 内容相同的候选合并后的所有来源, 只在搜索结果中返回
+	Sources []LyricSource `json:"sources,omitempty"`
+	// Provider 明确表示这是纯音乐 (LRCLIB 的 instrumental), 此时没有歌词内容
+	Instrumental bool `json:"instrumental,omitempty"`
+	// 根据其他候选估计的 offset, 只在搜索结果中返回
+	Estimate *OffsetEstimate `json:"estimate,omitempty"`
+	// 时间轴是根据纯文本歌词估计的, 只能大致跟随
+	Estimated bool `json:"estimated,omitempty"`
+	// 歌词和翻译的主要语言 (BCP 47, 拼音 / 罗马字为 zh-Latn / ja-Latn)
+	Language      string `json:"language,omitempty"`
+	TransLanguage string `json:"trans_language,omitempty"`
+	// 歌词其实是其他候选原文的翻译
+	Translated bool `json:"translated,omitempty"`
+	// 返回前经过清理 / 屏蔽 / 罗马音 / 字形转换, 与保存的原始内容不同
+	Transformed bool `json:"transformed,omitempty"`
 }
 
 type MusicRelationOffset struct {
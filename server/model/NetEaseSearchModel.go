@@ -1,12 +1,5 @@
 package model
 
-type NetEaseLyricsResponse struct {
-	SongStatus   int    `json:"songStatus"`
-	LyricVersion int    `json:"lyricVersion"`
-	Lyric        string `json:"lyric"`
-	Code         int    `json:"code"`
-}
-
 type NetEaseSearchResponse struct {
 	Result struct {
 		Songs []struct {
@@ -0,0 +1,14 @@
+package model
+
+type NowPlayingRequest struct {
+	// 房间名, 为空时使用用户默认房间
+	Room string `json:"room"`
+	// spotify 歌曲ID
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Singer string `json:"singer"`
+	// 播放进度 ms
+	Position int64   `json:"position"`
+	Playing  bool    `json:"playing"`
+	Rate     float64 `json:"rate"`
+}
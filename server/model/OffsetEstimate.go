@@ -0,0 +1,13 @@
+package model
+
+// OffsetEstimate 与同一首歌其他候选的时间轴比较后估计的 offset
+type OffsetEstimate struct {
+	// 建议的 offset (ms), 含义与 MusicRelation.Offset 相同
+	Offset int64 `json:"offset"`
+	// 0 ~ 1, 综合支持的候选比例, 参与比较的候选数和逐行时间差的一致程度
+	Confidence float64 `json:"confidence"`
+	// 能与之对齐的其他候选数, 同一份时间轴的副本只算一个
+	Peers int `json:"peers"`
+	// 是否已经自动应用到 Offset
+	Applied bool `json:"applied,omitempty"`
+}
@@ -0,0 +1,20 @@
+package model
+
+type ProviderHealth struct {
+	Name string `json:"name"`
+	// closed / open / half_open
+	State               string  `json:"state"`
+	Calls               int64   `json:"calls"`
+	Failures            int64   `json:"failures"`
+	Skipped             int64   `json:"skipped"`
+	SuccessRate         float64 `json:"success_rate"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	// 最近调用的耗时分位数 (ms)
+	LatencyP50  int64  `json:"latency_p50"`
+	LatencyP90  int64  `json:"latency_p90"`
+	LatencyP99  int64  `json:"latency_p99"`
+	LastError   string `json:"last_error,omitempty"`
+	LastErrorAt string `json:"last_error_at,omitempty"`
+	// 熔断后允许探测的时间
+	RetryAt string `json:"retry_at,omitempty"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// PublishRequest 发布歌词到 LRCLIB, Lyrics 为空时使用用户自己确认的歌词和 offset
+type PublishRequest struct {
+	// spotify 歌曲ID
+	Sid    string `json:"sid"`
+	Name   string `json:"name"`
+	Singer string `json:"singer"`
+	Album  string `json:"album"`
+	// 歌曲时长 ms
+	Duration int64 `json:"duration"`
+	// base64 歌词 (LRC / QRC / KRC / YRC)
+	Lyrics string `json:"lyrics"`
+	// 与 MusicRelation.Offset 相同, 发布前应用到每一行的时间
+	Offset int64 `json:"offset"`
+}
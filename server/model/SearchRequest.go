@@ -5,8 +5,24 @@ type SearchRequest struct {
 	Name string `json:"name"`
 	// 艺人
 	Singer string `json:"singer"`
+	// 专辑
+	Album string `json:"album"`
+	// 歌曲时长 ms
+	Duration int64 `json:"duration"`
 	// spotify 歌曲ID
 	Id string `json:"id"`
 	// 强制刷新
 	Refresh bool `json:"refresh"`
+	// 只需要逐行歌词, 不需要逐字时间 (部分 Provider 可以少下载/解密一次)
+	LineOnly bool `json:"line_only"`
+	// 返回文字的字形: s / t / tw / hk / original (默认, 不转换)
+	Script string `json:"script"`
+	// 罗马音: auto (默认, 没有时生成) / pinyin / jyutping / off
+	Roma string `json:"roma"`
+	// 跳过署名 / 广告 / 空行清理, 返回原始歌词
+	Raw bool `json:"raw"`
+	// 期望的原文语言 (BCP 47, 如 ja / zh / en), 排在前面的优先
+	Languages []string `json:"languages"`
+	// 是否屏蔽脏话, 为空时使用用户设置或配置的默认值
+	Mask *bool `json:"mask"`
 }
@@ -0,0 +1,35 @@
+package model
+
+type User struct {
+	Id        int64  `json:"id"`
+	Name      string `json:"name"`
+	Admin     bool   `json:"admin"`
+	Tokens    int    `json:"tokens"`
+	CreatedAt string `json:"created_at"`
+	// 是否屏蔽脏话, 为空时使用配置的默认值
+	MaskProfanity *bool `json:"mask_profanity,omitempty"`
+}
+
+// UserSettings 用户自己的偏好设置
+type UserSettings struct {
+	MaskProfanity *bool `json:"mask_profanity"`
+}
+
+type UserToken struct {
+	Id     int64  `json:"id"`
+	UserId int64  `json:"user_id"`
+	Prefix string `json:"prefix"`
+	// 明文 token 只在创建时返回一次, 数据库只保存哈希
+	Token     string `json:"token,omitempty"`
+	CreatedAt string `json:"created_at"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+}
+
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+type CreateTokenRequest struct {
+	UserId int64 `json:"user_id"`
+}
@@ -0,0 +1,26 @@
+package playback
+
+import "time"
+
+// Clock 客户端上报的播放进度, 两次上报之间按播放速率外推
+type Clock struct {
+	Position int64     `json:"position"`
+	Rate     float64   `json:"rate"`
+	Playing  bool      `json:"playing"`
+	At       time.Time `json:"at"`
+}
+
+// Now 外推 t 时刻的播放位置 (ms)
+func (c Clock) Now(t time.Time) int64 {
+	if !c.Playing {
+		return c.Position
+	}
+	return c.Position + int64(float64(t.Sub(c.At).Milliseconds())*c.rate())
+}
+
+func (c Clock) rate() float64 {
+	if c.Rate <= 0 {
+		return 1
+	}
+	return c.Rate
+}
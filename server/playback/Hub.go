@@ -0,0 +1,173 @@
+package playback
+
+import (
+	"errors"
+	"lyrics/model"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 没有订阅者且超过这么久没有上报的房间会被清理
+	roomTTL = time.Hour
+	// 清理的最小间隔
+	sweepInterval = time.Minute
+	// 每个用户最多的房间数和房间名长度
+	maxRooms    = 16
+	maxRoomName = 64
+)
+
+var (
+	ErrRoomName     = errors.New("room name is too long")
+	ErrTooManyRooms = errors.New("too many rooms")
+)
+
+// NowPlaying 某个房间当前播放的歌曲, Relation 为空表示歌词还在解析或者没有找到
+type NowPlaying struct {
+	Sid      string               `json:"sid"`
+	Name     string               `json:"name"`
+	Singer   string               `json:"singer"`
+	Clock    Clock                `json:"clock"`
+	Relation *model.MusicRelation `json:"-"`
+}
+
+// Hub 按用户 / 房间保存上报的播放状态, 房间名由客户端指定, 所以限制数量并清理闲置的房间
+type Hub struct {
+	mu    sync.Mutex
+	rooms map[roomKey]*Room
+	swept time.Time
+	now   func() time.Time
+}
+
+type roomKey struct {
+	owner string
+	name  string
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: map[roomKey]*Room{}, now: time.Now}
+}
+
+// Room 返回 owner 的房间, 不存在时创建; 房间名过长或房间数超过上限时返回错误
+func (h *Hub) Room(owner string, name string) (*Room, error) {
+	if utf8.RuneCountInString(name) > maxRoomName {
+		return nil, ErrRoomName
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweep()
+	key := roomKey{owner, name}
+	if room, ok := h.rooms[key]; ok {
+		return room, nil
+	}
+	count := 0
+	for k := range h.rooms {
+		if k.owner == owner {
+			count++
+		}
+	}
+	if count >= maxRooms {
+		return nil, ErrTooManyRooms
+	}
+	room := &Room{subscribers: map[chan NowPlaying]struct{}{}, now: h.now, updated: h.now()}
+	h.rooms[key] = room
+	return room, nil
+}
+
+// Find 只查找已有的房间, 不会创建
+func (h *Hub) Find(owner string, name string) (*Room, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room, ok := h.rooms[roomKey{owner, name}]
+	return room, ok
+}
+
+// sweep 清理没有订阅者且长时间没有上报的房间, 调用方持有 h.mu
+func (h *Hub) sweep() {
+	now := h.now()
+	if now.Sub(h.swept) < sweepInterval {
+		return
+	}
+	h.swept = now
+	for key, room := range h.rooms {
+		if room.idle(now) {
+			delete(h.rooms, key)
+		}
+	}
+}
+
+type Room struct {
+	mu          sync.Mutex
+	state       NowPlaying
+	subscribers map[chan NowPlaying]struct{}
+	// 最后一次上报 / 订阅变化的时间
+	updated time.Time
+	now     func() time.Time
+}
+
+func (r *Room) idle(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.subscribers) == 0 && now.Sub(r.updated) > roomTTL
+}
+
+func (r *Room) State() NowPlaying {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Publish 更新播放状态并通知所有订阅者
+func (r *Room) Publish(state NowPlaying) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = state
+	r.updated = r.now()
+	r.broadcast()
+}
+
+// Resolve 歌词解析完成后补充到状态里, 期间已经切歌时忽略
+func (r *Room) Resolve(sid string, relation model.MusicRelation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state.Sid != sid {
+		return
+	}
+	r.state.Relation = &relation
+	r.updated = r.now()
+	r.broadcast()
+}
+
+// Subscribe 订阅状态变化, 订阅时立即收到当前状态; 订阅者处理不过来时只保留最新的状态
+func (r *Room) Subscribe() (chan NowPlaying, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := make(chan NowPlaying, 1)
+	r.subscribers[ch] = struct{}{}
+	r.updated = r.now()
+	if r.state.Sid != "" {
+		ch <- r.state
+	}
+	return ch, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subscribers, ch)
+		r.updated = r.now()
+	}
+}
+
+func (r *Room) broadcast() {
+	for ch := range r.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- r.state
+	}
+}
+
+// Position 外推当前的播放位置
+func (state NowPlaying) Position() int64 {
+	return state.Clock.Now(time.Now())
+}
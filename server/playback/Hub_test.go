@@ -0,0 +1,54 @@
+package playback
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHubLimits(t *testing.T) {
+	h := NewHub()
+	if _, err := h.Room("1", strings.Repeat("r", maxRoomName+1)); !errors.Is(err, ErrRoomName) {
+		t.Errorf("long name: err = %v", err)
+	}
+	for i := 0; i < maxRooms; i++ {
+		if _, err := h.Room("1", strconv.Itoa(i)); err != nil {
+			t.Fatalf("room %d: %v", i, err)
+		}
+	}
+	if _, err := h.Room("1", "extra"); !errors.Is(err, ErrTooManyRooms) {
+		t.Errorf("extra room: err = %v", err)
+	}
+	// 已有的房间和其他用户不受影响
+	if _, err := h.Room("1", "0"); err != nil {
+		t.Errorf("existing room: %v", err)
+	}
+	if _, err := h.Room("2", "extra"); err != nil {
+		t.Errorf("other owner: %v", err)
+	}
+}
+
+func TestHubEvictsIdleRooms(t *testing.T) {
+	now := time.Unix(0, 0)
+	h := NewHub()
+	h.now = func() time.Time { return now }
+
+	idle, _ := h.Room("1", "idle")
+	idle.Publish(NowPlaying{Sid: "a"})
+	followed, _ := h.Room("1", "followed")
+	_, cancel := followed.Subscribe()
+	defer cancel()
+
+	now = now.Add(roomTTL + sweepInterval)
+	if _, err := h.Room("1", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Find("1", "idle"); ok {
+		t.Error("idle room was not evicted")
+	}
+	if _, ok := h.Find("1", "followed"); !ok {
+		t.Error("room with subscribers was evicted")
+	}
+}
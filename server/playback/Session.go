@@ -0,0 +1,330 @@
+package playback
+
+import (
+	"lyrics/lyric"
+	"lyrics/model"
+	"sync"
+	"time"
+)
+
+const (
+	// 没有变化时最长的检查间隔
+	idleWait     = time.Second
+	minWait      = 5 * time.Millisecond
+	pingInterval = 30 * time.Second
+)
+
+// Loader 按 spotify 歌曲 ID 读取已经保存的歌词
+type Loader func(sid string) (model.MusicRelation, bool)
+
+// Transform 推送前对歌词做的处理, 例如简繁转换
+type Transform func(relation model.MusicRelation) model.MusicRelation
+
+// Rooms 按房间名找到可以跟随的 now playing 房间
+type Rooms func(room string) (*Room, error)
+
+// Sink 推送通道 (WebSocket / SSE)
+type Sink interface {
+	Write(event Event) error
+	Ping() error
+}
+
+// Message 客户端发来的消息
+//
+//	{"type":"subscribe","sid":"...","position":12000,"playing":true}
+//	{"type":"position","position":15000,"rate":1,"playing":true}
+//	{"type":"follow","room":"living-room"}
+type Message struct {
+	Type     string  `json:"type"`
+	Sid      string  `json:"sid,omitempty"`
+	Room     string  `json:"room,omitempty"`
+	Position int64   `json:"position"`
+	Rate     float64 `json:"rate"`
+	Playing  *bool   `json:"playing"`
+}
+
+// Event 推送给客户端的消息, type 为 lyrics / nowplaying / line / word / error
+type Event struct {
+	Type     string      `json:"type"`
+	Sid      string      `json:"sid,omitempty"`
+	Lid      string      `json:"lid,omitempty"`
+	Name     string      `json:"name,omitempty"`
+	Singer   string      `json:"singer,omitempty"`
+	Offset   int64       `json:"offset,omitempty"`
+	Lines    int         `json:"lines,omitempty"`
+	Position int64       `json:"position"`
+	Playing  bool        `json:"playing,omitempty"`
+	Rate     float64     `json:"rate,omitempty"`
+	Index    int         `json:"index"`
+	LineNo   int         `json:"line_index"`
+	Active   bool        `json:"active,omitempty"`
+	Progress float64     `json:"progress,omitempty"`
+	Line     *lyric.Line `json:"line,omitempty"`
+	Word     *lyric.Word `json:"word,omitempty"`
+	Message  string      `json:"message,omitempty"`
+}
+
+type Session struct {
+	sink      Sink
+	load      Loader
+	rooms     Rooms
+	transform Transform
+
+	mu       sync.Mutex
+	relation model.MusicRelation
+	lyric    lyric.Lyric
+	loaded   bool
+	clock    Clock
+	sent     lyric.Position
+	unfollow func()
+
+	wake      chan struct{}
+	out       chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSession(sink Sink, load Loader, rooms Rooms) *Session {
+	return &Session{
+		sink:  sink,
+		load:  load,
+		rooms: rooms,
+		sent:  lyric.Position{Line: -2, Word: -2},
+		wake:  make(chan struct{}, 1),
+		out:   make(chan Event, 16),
+		done:  make(chan struct{}),
+	}
+}
+
+// Serve 持续推送直到 Close 或者写入失败
+func (s *Session) Serve() {
+	defer s.Close()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.out:
+			if s.sink.Write(event) != nil {
+				return
+			}
+		case <-ping.C:
+			if s.sink.Ping() != nil {
+				return
+			}
+		case <-s.wake:
+		case <-timer.C:
+		}
+		// 先把排队的 lyrics / nowplaying 发出去, 保证行事件在它们之后
+		if !s.flush() {
+			return
+		}
+		events, wait := s.tick(time.Now())
+		for _, event := range events {
+			if s.sink.Write(event) != nil {
+				return
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (s *Session) flush() bool {
+	for {
+		select {
+		case event := <-s.out:
+			if s.sink.Write(event) != nil {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.unfollow != nil {
+			s.unfollow()
+		}
+	})
+}
+
+// SetTransform 设置之后订阅 / 跟随到的歌词都会先经过 transform
+func (s *Session) SetTransform(transform Transform) {
+	s.mu.Lock()
+	s.transform = transform
+	s.mu.Unlock()
+}
+
+// Subscribe 切换到一首歌的歌词, 之后的推送从头开始计算
+func (s *Session) Subscribe(relation model.MusicRelation) {
+	s.mu.Lock()
+	if s.transform != nil {
+		relation = s.transform(relation)
+	}
+	s.relation = relation
+	s.lyric = lyric.Parse(lyric.Decode(relation.Lyrics))
+	s.loaded = true
+	s.sent = lyric.Position{Line: -2, Word: -2}
+	lines := len(s.lyric.Lines)
+	s.mu.Unlock()
+
+	s.Send(Event{
+		Type:   "lyrics",
+		Sid:    relation.Sid,
+		Lid:    relation.Lid,
+		Name:   relation.Name,
+		Singer: relation.Singer,
+		Offset: relation.Offset,
+		Lines:  lines,
+	})
+}
+
+// Report 更新播放进度
+func (s *Session) Report(clock Clock) {
+	s.mu.Lock()
+	s.clock = clock
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Follow 跟随房间里上报的播放状态, 代替客户端自己上报
+func (s *Session) Follow(room *Room) {
+	updates, cancel := room.Subscribe()
+	s.mu.Lock()
+	if s.unfollow != nil {
+		s.unfollow()
+	}
+	s.unfollow = cancel
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-s.done:
+				return
+			case state, ok := <-updates:
+				if !ok {
+					return
+				}
+				s.apply(state)
+			}
+		}
+	}()
+}
+
+func (s *Session) apply(state NowPlaying) {
+	s.Send(Event{
+		Type:     "nowplaying",
+		Sid:      state.Sid,
+		Name:     state.Name,
+		Singer:   state.Singer,
+		Position: state.Clock.Now(time.Now()),
+		Playing:  state.Clock.Playing,
+		Rate:     state.Clock.rate(),
+	})
+
+	s.mu.Lock()
+	changed := state.Relation != nil && (!s.loaded || s.relation.Sid != state.Relation.Sid || s.relation.Lid != state.Relation.Lid)
+	stale := state.Relation == nil && s.loaded && s.relation.Sid != state.Sid
+	if stale {
+		s.loaded = false
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.Subscribe(*state.Relation)
+	}
+	s.Report(state.Clock)
+}
+
+func (s *Session) Send(event Event) {
+	select {
+	case s.out <- event:
+	case <-s.done:
+	}
+}
+
+func (s *Session) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Session) Handle(message Message) {
+	switch message.Type {
+	case "subscribe":
+		relation, ok := s.load(message.Sid)
+		if !ok {
+			s.Send(Event{Type: "error", Sid: message.Sid, Message: "lyrics not found, search it first"})
+			return
+		}
+		s.Subscribe(relation)
+		s.Report(s.clockOf(message))
+	case "position":
+		s.Report(s.clockOf(message))
+	case "follow":
+		if s.rooms == nil {
+			s.Send(Event{Type: "error", Message: "follow is not supported"})
+			return
+		}
+		room, err := s.rooms(message.Room)
+		if err != nil {
+			s.Send(Event{Type: "error", Message: err.Error()})
+			return
+		}
+		s.Follow(room)
+	default:
+		s.Send(Event{Type: "error", Message: "unknown message type " + message.Type})
+	}
+}
+
+// 没有带 playing 时沿用上一次的播放状态
+func (s *Session) clockOf(message Message) Clock {
+	s.mu.Lock()
+	playing := s.clock.Playing
+	s.mu.Unlock()
+	if message.Playing != nil {
+		playing = *message.Playing
+	}
+	return Clock{Position: message.Position, Rate: message.Rate, Playing: playing, At: time.Now()}
+}
+
+// tick 计算当前行 / 字, 有变化时生成事件, 并返回距离下一次变化的等待时间
+func (s *Session) tick(now time.Time) ([]Event, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded {
+		return nil, idleWait
+	}
+
+	var events []Event
+	ms := s.clock.Now(now) + s.relation.Offset
+	position := s.lyric.Locate(ms)
+	lineChanged := position.Line != s.sent.Line || position.Active != s.sent.Active
+	if lineChanged && position.Line >= 0 {
+		line := s.lyric.Lines[position.Line]
+		events = append(events, Event{Type: "line", Sid: s.relation.Sid, Position: ms, Index: position.Line, LineNo: position.Line, Active: position.Active, Line: &line})
+	}
+	if (lineChanged || position.Word != s.sent.Word) && position.Word >= 0 {
+		word := s.lyric.Lines[position.Line].Words[position.Word]
+		events = append(events, Event{Type: "word", Sid: s.relation.Sid, Position: ms, LineNo: position.Line, Index: position.Word, Progress: position.Progress, Word: &word})
+	}
+	s.sent = position
+
+	next := s.lyric.NextChange(ms)
+	if next < 0 || !s.clock.Playing {
+		return events, idleWait
+	}
+	wait := time.Duration(float64(next-ms)/s.clock.rate()) * time.Millisecond
+	return events, min(max(wait, minWait), idleWait)
+}
@@ -0,0 +1,109 @@
+package playback
+
+import (
+	"encoding/base64"
+	"lyrics/model"
+	"testing"
+	"time"
+)
+
+func TestSessionTick(t *testing.T) {
+	// 第一行两个字, 第二行没有逐字
+	content := base64.StdEncoding.EncodeToString([]byte("[1000,2000](1000,500,0)a(1500,500,0)b\n[4000,2000]plain"))
+	now := time.Unix(1000, 0)
+	type event struct {
+		kind   string
+		line   int
+		index  int
+		active bool
+	}
+	cases := []struct {
+		name   string
+		offset int64
+		clock  Clock
+		events []event
+		wait   time.Duration
+	}{
+		{
+			// 歌词时间 = 播放进度 + offset
+			name:   "offset reaches first line",
+			offset: 500,
+			clock:  Clock{Position: 500},
+			events: []event{{"line", 0, 0, true}, {"word", 0, 0, false}},
+			wait:   idleWait,
+		},
+		{
+			name:   "negative offset before first line",
+			offset: -500,
+			clock:  Clock{Position: 1200, Playing: true},
+			wait:   300 * time.Millisecond,
+		},
+		{
+			name:   "offset moves to second word",
+			offset: 500,
+			clock:  Clock{Position: 2300, Playing: true},
+			events: []event{{"line", 0, 0, true}, {"word", 0, 1, false}},
+			wait:   200 * time.Millisecond,
+		},
+		{
+			name:   "rate shortens wait",
+			clock:  Clock{Position: 1000, Rate: 2, Playing: true},
+			events: []event{{"line", 0, 0, true}, {"word", 0, 0, false}},
+			wait:   250 * time.Millisecond,
+		},
+		{
+			name:   "min wait",
+			clock:  Clock{Position: 1499, Playing: true},
+			events: []event{{"line", 0, 0, true}, {"word", 0, 0, false}},
+			wait:   minWait,
+		},
+		{
+			name:   "gap between lines",
+			offset: 1000,
+			clock:  Clock{Position: 2500, Playing: true},
+			events: []event{{"line", 0, 0, false}, {"word", 0, 1, false}},
+			wait:   500 * time.Millisecond,
+		},
+		{
+			name:   "after last line",
+			offset: -1000,
+			clock:  Clock{Position: 8000, Playing: true},
+			events: []event{{"line", 1, 1, false}},
+			wait:   idleWait,
+		},
+	}
+	for _, c := range cases {
+		s := NewSession(nil, nil, nil)
+		s.Subscribe(model.MusicRelation{Sid: "sid", Lyrics: content, Offset: c.offset})
+		c.clock.At = now
+		s.Report(c.clock)
+
+		events, wait := s.tick(now)
+		if len(events) != len(c.events) {
+			t.Errorf("%s: %d events, want %d: %+v", c.name, len(events), len(c.events), events)
+			continue
+		}
+		ms := c.clock.Position + c.offset
+		for i, want := range c.events {
+			got := events[i]
+			if got.Type != want.kind || got.LineNo != want.line || got.Index != want.index || got.Active != want.active || got.Position != ms {
+				t.Errorf("%s: event %d = %+v, want %+v at %d", c.name, i, got, want, ms)
+			}
+		}
+		if wait != c.wait {
+			t.Errorf("%s: wait = %s, want %s", c.name, wait, c.wait)
+		}
+		// 没有变化时不重复推送
+		if events, _ := s.tick(now); len(events) != 0 {
+			t.Errorf("%s: repeated events %+v", c.name, events)
+		}
+	}
+}
+
+func TestSessionTickNotLoaded(t *testing.T) {
+	s := NewSession(nil, nil, nil)
+	s.Report(Clock{Position: 1000, Playing: true, At: time.Now()})
+	if events, wait := s.tick(time.Now()); len(events) != 0 || wait != idleWait {
+		t.Errorf("tick = %+v, %s", events, wait)
+	}
+}
@@ -0,0 +1,54 @@
+package playback
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeTimeout = 10 * time.Second
+	maxMessage   = 4096
+)
+
+type socketSink struct {
+	conn *websocket.Conn
+}
+
+func (sink socketSink) Write(event Event) error {
+	_ = sink.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	err := sink.conn.WriteJSON(event)
+	if err != nil {
+		log.Printf("[ERROR] Failed Write Sync Event %s", err)
+	}
+	return err
+}
+
+func (sink socketSink) Ping() error {
+	return sink.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+// ServeSocket 读取客户端消息并推送事件, 阻塞直到连接断开
+func ServeSocket(conn *websocket.Conn, load Loader, rooms Rooms, transform Transform) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	session := NewSession(socketSink{conn: conn}, load, rooms)
+	session.SetTransform(transform)
+	go func() {
+		defer session.Close()
+		conn.SetReadLimit(maxMessage)
+		for {
+			var message Message
+			if err := conn.ReadJSON(&message); err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.Printf("[INFO] Sync Session Closed %s", err)
+				}
+				return
+			}
+			session.Handle(message)
+		}
+	}()
+	session.Serve()
+}
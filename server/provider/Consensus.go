@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"lyrics/config"
+	"lyrics/lyric"
+	"lyrics/model"
+	"math"
+	"slices"
+)
+
+const (
+	// 两份歌词至少有这么多相同的行才比较时间轴
+	consensusMinLines = 3
+	// 时间差与中位数相差不超过这个值 (ms) 时视为一致
+	consensusTolerance = 200
+	// 参与比较的候选达到这个数时不再因为样本少而降低置信度
+	consensusPeers = 3
+	// 逐行时间差不超过这个值 (ms) 的行占相同行的比例达到 duplicateSimilarity 时, 视为同一份时间轴的副本
+	copyTolerance = 10
+)
+
+type timedLine struct {
+	text  string
+	start int64
+}
+
+// peerDelta 一个候选与另一个候选相同行的时间差 (本候选 - 对方) 的中位数, 以及逐行时间差落在中位数附近的比例
+type peerDelta struct {
+	delta       int64
+	consistency float64
+}
+
+// Consensus 逐行对齐所有候选的时间轴, 以多数候选的时间为准估计每个候选的 offset 写入 Estimate;
+// 同一份时间轴的多个副本 (如 QQ 和 QQ LK, Kugou 和 Kugou LK) 只算一票, 也不与自己的副本比较.
+// 开启 auto_apply 时把置信度足够且偏差足够大的估计直接写入还没有 offset 的候选
+func Consensus(data []model.MusicRelation) []model.MusicRelation {
+	timelines := make([][]timedLine, len(data))
+	for i, relation := range data {
+		timelines[i] = timeline(relation)
+	}
+	// clusters[i] 为第 i 个候选所属副本组中第一个候选的下标
+	clusters := make([]int, len(data))
+	for i := range data {
+		clusters[i] = i
+		for j := 0; j < i; j++ {
+			if clusters[j] == j && sameTimeline(timelines[i], timelines[j]) {
+				clusters[i] = j
+				break
+			}
+		}
+	}
+
+	for i := range data {
+		var peers []peerDelta
+		counted := map[int]bool{clusters[i]: true}
+		for j := range data {
+			if counted[clusters[j]] {
+				continue
+			}
+			if d, ok := compareTimeline(timelines[i], timelines[j]); ok {
+				peers = append(peers, d)
+				counted[clusters[j]] = true
+			}
+		}
+		if len(peers) == 0 {
+			continue
+		}
+		estimate := estimateOffset(peers)
+		options := config.C.Consensus
+		if options.AutoApply && data[i].Offset == 0 && estimate.Confidence >= options.MinConfidence &&
+			abs(estimate.Offset) >= options.MinShift.Milliseconds() {
+			data[i].Offset = estimate.Offset
+			estimate.Applied = true
+		}
+		data[i].Estimate = &estimate
+	}
+	return data
+}
+
+// timeline 清理后有文字的行, 没有时间戳的纯文本歌词返回空
+func timeline(relation model.MusicRelation) []timedLine {
+	parsed := lyric.Parse(lyric.Clean(lyric.Decode(relation.Lyrics), fingerprintOptions, nil))
+	var result []timedLine
+	synced := false
+	for _, line := range parsed.Lines {
+		if text := normalizeLine(line.Text); text != "" {
+			result = append(result, timedLine{text: text, start: line.Start})
+			synced = synced || line.Start > 0
+		}
+	}
+	if !synced {
+		return nil
+	}
+	return result
+}
+
+// compareTimeline 按顺序匹配文字相同的行, 相同的行太少或时间差不一致 (不是整体平移) 时不比较
+func compareTimeline(a []timedLine, b []timedLine) (peerDelta, bool) {
+	deltas, ok := matchLines(a, b)
+	if !ok {
+		return peerDelta{}, false
+	}
+
+	slices.Sort(deltas)
+	median := deltas[len(deltas)/2]
+	near := 0
+	for _, d := range deltas {
+		if abs(d-median) <= consensusTolerance {
+			near++
+		}
+	}
+	consistency := float64(near) / float64(len(deltas))
+	if consistency < 0.5 {
+		return peerDelta{}, false
+	}
+	return peerDelta{delta: median, consistency: consistency}, true
+}
+
+// matchLines 按顺序匹配文字相同的行, 返回每对行的时间差 (a - b), 相同的行太少时返回 false
+func matchLines(a []timedLine, b []timedLine) ([]int64, bool) {
+	positions := map[string][]int{}
+	for j, line := range b {
+		positions[line.text] = append(positions[line.text], j)
+	}
+	var deltas []int64
+	last := -1
+	for _, line := range a {
+		for _, j := range positions[line.text] {
+			if j > last {
+				deltas = append(deltas, line.start-b[j].start)
+				last = j
+				break
+			}
+		}
+	}
+	return deltas, len(deltas) >= max(consensusMinLines, min(len(a), len(b))*3/10)
+}
+
+// sameTimeline 两个时间轴几乎逐行相同, 是同一份歌词的副本而不是独立的时间轴
+func sameTimeline(a []timedLine, b []timedLine) bool {
+	deltas, ok := matchLines(a, b)
+	if !ok {
+		return false
+	}
+	same := 0
+	for _, d := range deltas {
+		if abs(d) <= copyTolerance {
+			same++
+		}
+	}
+	return float64(same) >= duplicateSimilarity*float64(max(len(a), len(b)))
+}
+
+// estimateOffset 取相互一致的时间差最多的一组 (一样多时取偏差最小的), 组内平均值即 offset;
+// 置信度 = 组内候选比例 * 候选数量 (不足 consensusPeers 时按比例折算) * 组内逐行一致程度
+func estimateOffset(peers []peerDelta) model.OffsetEstimate {
+	var best []peerDelta
+	var center int64
+	for _, p := range peers {
+		var group []peerDelta
+		for _, q := range peers {
+			if abs(q.delta-p.delta) <= consensusTolerance {
+				group = append(group, q)
+			}
+		}
+		if len(group) > len(best) || len(group) == len(best) && abs(p.delta) < abs(center) {
+			best, center = group, p.delta
+		}
+	}
+
+	var sum int64
+	consistency := 0.0
+	for _, p := range best {
+		sum += p.delta
+		consistency += p.consistency
+	}
+	consistency /= float64(len(best))
+	agreement := float64(len(best)) / float64(len(peers))
+	coverage := float64(min(len(peers), consensusPeers)) / consensusPeers
+	return model.OffsetEstimate{
+		Offset:     sum / int64(len(best)),
+		Confidence: math.Round(agreement*coverage*consistency*100) / 100,
+		Peers:      len(peers),
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"lyrics/model"
+	"strconv"
+	"testing"
+)
+
+func TestConsensus(t *testing.T) {
+	// 三条时间轴基本一致 (相差几十毫秒), 最后一条整体推迟 1.5 秒
+	var data []model.MusicRelation
+	for i, shift := range []int64{0, 30, -40, 1500} {
+		data = append(data, syntheticRelation(strconv.Itoa(i), syntheticTexts, shift))
+	}
+
+	result := Consensus(data)
+	for i, want := range []int64{0, 30, -40, 1500} {
+		estimate := result[i].Estimate
+		if estimate == nil {
+			t.Fatalf("%d: no estimate", i)
+		}
+		if abs(estimate.Offset-want) > 50 || estimate.Peers != 3 || estimate.Confidence < 0.6 {
+			t.Errorf("%d: estimate = %+v, want offset %d", i, *estimate, want)
+		}
+	}
+}
+
+// 同一份时间轴的两个副本只算一票, 不能压过两条独立的时间轴
+func TestConsensusCopies(t *testing.T) {
+	data := []model.MusicRelation{
+		syntheticRelation("good", syntheticTexts, 0),
+		syntheticRelation("good too", syntheticTexts, 30),
+		syntheticRelation("shifted", syntheticTexts, 1500),
+		syntheticRelation("shifted copy", syntheticTexts, 1500),
+	}
+	result := Consensus(data)
+	for i, want := range []struct {
+		offset int64
+		peers  int
+	}{{-30, 2}, {30, 2}, {1485, 2}, {1485, 2}} {
+		estimate := result[i].Estimate
+		if estimate == nil {
+			t.Fatalf("%d: no estimate", i)
+		}
+		if abs(estimate.Offset-want.offset) > 30 || estimate.Peers != want.peers {
+			t.Errorf("%s: estimate = %+v, want offset %d with %d peers", result[i].Lid, *estimate, want.offset, want.peers)
+		}
+	}
+}
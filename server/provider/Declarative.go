@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	apputils "lyrics/app-utils"
+	"lyrics/config"
+	"lyrics/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 声明式 Provider 单次响应的最大长度
+const declarativeMaxBody = 4 << 20
+
+// Declarative 由配置定义的 HTTP/JSON 歌词源
+type Declarative struct {
+	Definition config.DeclarativeProvider
+}
+
+// Declaratives 校验配置中的定义, 不完整的定义会被跳过
+// 名字作为歌词类型和出站客户端的 key, 与内置 Provider 或前面的定义重名时跳过
+func Declaratives(definitions []config.DeclarativeProvider) []Provider {
+	var result []Provider
+	seen := map[string]bool{}
+	for _, definition := range definitions {
+		err := validateDefinition(definition)
+		if err == nil && seen[strings.ToLower(definition.Name)] {
+			err = errors.New("duplicate name")
+		}
+		if err != nil {
+			log.Printf("[ERROR] Skip Declarative Provider [%s]: %s", definition.Name, err)
+			continue
+		}
+		seen[strings.ToLower(definition.Name)] = true
+		log.Printf("[INFO] Declarative Provider Registered [%s]", definition.Name)
+		result = append(result, Declarative{Definition: definition})
+	}
+	return result
+}
+
+func validateDefinition(definition config.DeclarativeProvider) error {
+	if definition.Name == "" {
+		return errors.New("name is required")
+	}
+	if reservedName(definition.Name) {
+		return fmt.Errorf("name %q is used by a built-in provider", definition.Name)
+	}
+	if definition.Search.URL == "" {
+		return errors.New("search.url is required")
+	}
+	if definition.Fetch == nil && definition.Fields["lyrics"] == "" {
+		return errors.New("fields.lyrics or fetch is required")
+	}
+	if definition.Fetch != nil && definition.Fields["id"] == "" {
+		return errors.New("fields.id is required when fetch is used")
+	}
+	if d := definition.Decode; d != "" && d != "plain" && d != "base64" {
+		return fmt.Errorf("unknown decode %q", d)
+	}
+	return nil
+}
+
+func (d Declarative) Name() string {
+	return d.Definition.Name
+}
+
+func (d Declarative) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
+	var result []model.MusicRelation
+	definition := d.Definition
+
+	vars := map[string]string{
+		"name":       request.Name,
+		"singer":     request.Singer,
+		"album":      request.Album,
+		"query":      strings.TrimSpace(request.Name + " " + request.Singer),
+		"duration":   strconv.FormatInt(request.Duration, 10),
+		"duration_s": strconv.FormatInt((request.Duration+500)/1000, 10),
+	}
+	data, err := d.request(definition.Search.DeclarativeRequest, vars)
+	if err != nil {
+		return result, err
+	}
+
+	limit := definition.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	items := selectPath(data, definition.Search.Items)
+	// data.songs 选中的是整个数组, 等价于 data.songs[*]
+	if len(items) == 1 {
+		if array, ok := items[0].([]any); ok {
+			items = array
+		}
+	}
+	for i, item := range items {
+		if i >= limit {
+			break
+		}
+		id := selectString(item, definition.Fields["id"])
+		source := item
+		fields := definition.Fields
+		if definition.Fetch != nil {
+			vars["id"] = id
+			fetched, err := d.request(*definition.Fetch, vars)
+			if err != nil {
+				log.Printf("[ERROR] Failed Fetch %s [%s]: %s", definition.Name, id, err)
+				continue
+			}
+			source = fetched
+			fields = mergeFields(definition.Fields, definition.Fetch.Fields)
+		}
+
+		lyrics := d.decode(selectString(source, fields["lyrics"]))
+		if strings.TrimSpace(lyrics) == "" {
+			continue
+		}
+		result = append(result, model.MusicRelation{
+			Name:   selectString(item, definition.Fields["title"]),
+			Singer: selectString(item, definition.Fields["artist"]),
+			Lid:    id,
+			Sid:    request.Id,
+			Lyrics: base64.StdEncoding.EncodeToString([]byte(lyrics)),
+			Trans:  encodeOptional(d.decode(selectString(source, fields["trans"]))),
+			Type:   definition.Name,
+			Offset: 0,
+		})
+	}
+	return result, nil
+}
+
+func (d Declarative) decode(content string) string {
+	if d.Definition.Decode != "base64" || content == "" {
+		return content
+	}
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		log.Printf("[ERROR] Failed Decode %s Lyrics: %s", d.Definition.Name, err)
+		return ""
+	}
+	return string(decoded)
+}
+
+func (d Declarative) request(definition config.DeclarativeRequest, vars map[string]string) (any, error) {
+	method := strings.ToUpper(definition.Method)
+	if method == "" {
+		method = "GET"
+	}
+	target := expand(definition.URL, vars, url.QueryEscape)
+
+	var body io.Reader
+	if definition.Body != "" {
+		body = strings.NewReader(expand(definition.Body, vars, jsonEscape))
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	// 声明式 Provider 只用于查询, POST 也可以安全重试
+	req = apputils.AllowRetry(req)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range definition.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := apputils.Client(d.Definition.Name).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%s] API Response %d", target, resp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, declarativeMaxBody))
+	if err != nil {
+		return nil, err
+	}
+	var data any
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("[%s] Not Format JSON: %s", target, err)
+	}
+	return data, nil
+}
+
+// expand 替换 {{var}} 占位符, escape 决定值的转义方式
+func expand(template string, vars map[string]string, escape func(string) string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{{"+k+"}}", escape(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func jsonEscape(value string) string {
+	data, _ := json.Marshal(value)
+	return string(data[1 : len(data)-1])
+}
+
+func mergeFields(base map[string]string, override map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
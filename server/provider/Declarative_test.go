@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"lyrics/config"
+	"lyrics/lyric"
+	"lyrics/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefinition(t *testing.T) {
+	valid := config.DeclarativeProvider{
+		Name:   "Mine",
+		Search: config.DeclarativeSearch{DeclarativeRequest: config.DeclarativeRequest{URL: "http://example/search"}},
+		Fields: map[string]string{"lyrics": "lrc"},
+	}
+	cases := []struct {
+		name   string
+		modify func(d *config.DeclarativeProvider)
+		ok     bool
+	}{
+		{"valid", func(d *config.DeclarativeProvider) {}, true},
+		{"no name", func(d *config.DeclarativeProvider) { d.Name = "" }, false},
+		// 名字是歌词类型和出站客户端的 key, 不能与内置 Provider 相同
+		{"built-in type", func(d *config.DeclarativeProvider) { d.Name = QQMusicLKType }, false},
+		{"built-in client", func(d *config.DeclarativeProvider) { d.Name = "netEasemusic" }, false},
+		{"no url", func(d *config.DeclarativeProvider) { d.Search.URL = "" }, false},
+		{"no lyrics", func(d *config.DeclarativeProvider) { d.Fields = nil }, false},
+		{"fetch without id", func(d *config.DeclarativeProvider) { d.Fetch = &config.DeclarativeRequest{URL: "http://example/lyric"} }, false},
+		{"fetch with id", func(d *config.DeclarativeProvider) {
+			d.Fetch = &config.DeclarativeRequest{URL: "http://example/lyric"}
+			d.Fields = map[string]string{"id": "id"}
+		}, true},
+		{"unknown decode", func(d *config.DeclarativeProvider) { d.Decode = "gzip" }, false},
+	}
+	for _, c := range cases {
+		definition := valid
+		c.modify(&definition)
+		if err := validateDefinition(definition); (err == nil) != c.ok {
+			t.Errorf("%s: validateDefinition = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+
+	// 与前面的定义重名的被跳过
+	if got := Declaratives([]config.DeclarativeProvider{valid, valid, {Name: "Broken"}}); len(got) != 1 {
+		t.Errorf("Declaratives = %d providers, want 1", len(got))
+	}
+}
+
+// declarativeStandIn 搜索返回两首歌, 第二步按 id 返回 base64 歌词
+func declarativeStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			var query string
+			if r.Method == http.MethodPost {
+				body, _ := io.ReadAll(r.Body)
+				var payload map[string]string
+				_ = json.Unmarshal(body, &payload)
+				query = payload["q"]
+			} else {
+				query = r.URL.Query().Get("q")
+			}
+			if query != `晴天 "周杰伦"` {
+				http.Error(w, "bad query "+query, http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"result": {"songs": [
+				{"id": 7, "title": "晴天", "artist": "周杰伦", "lrc": "[00:01.00]故事的小黄花"},
+				{"id": 8, "title": "晴天 (Live)", "artist": "周杰伦", "lrc": ""}
+			]}}`))
+		case "/lyric":
+			if r.URL.Query().Get("id") != "7" {
+				http.NotFound(w, r)
+				return
+			}
+			encoded := base64.StdEncoding.EncodeToString([]byte("[00:01.00]故事的小黄花"))
+			_, _ = w.Write([]byte(`{"data": {"lyric": "` + encoded + `"}}`))
+		case "/broken":
+			_, _ = w.Write([]byte(`not json`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeclarativeLyrics(t *testing.T) {
+	server := declarativeStandIn(t)
+	request := model.SearchRequest{Id: "sid", Name: "晴天", Singer: `"周杰伦"`}
+	search := func(path string) config.DeclarativeSearch {
+		return config.DeclarativeSearch{DeclarativeRequest: config.DeclarativeRequest{URL: server.URL + path}, Items: "result.songs"}
+	}
+
+	cases := []struct {
+		name       string
+		definition config.DeclarativeProvider
+		lids       []string
+	}{
+		{
+			// 没有歌词的结果被跳过
+			name: "search fields",
+			definition: config.DeclarativeProvider{Name: "Get", Search: search("/search?q={{query}}"),
+				Fields: map[string]string{"id": "id", "title": "title", "artist": "artist", "lyrics": "lrc"}},
+			lids: []string{"7"},
+		},
+		{
+			// POST body 中的值按 JSON 转义
+			name: "post body",
+			definition: config.DeclarativeProvider{Name: "Post", Search: config.DeclarativeSearch{
+				DeclarativeRequest: config.DeclarativeRequest{URL: server.URL + "/search", Method: "post", Body: `{"q": "{{name}} {{singer}}"}`},
+				Items:              "result.songs[*]",
+			}, Fields: map[string]string{"id": "id", "lyrics": "lrc"}},
+			lids: []string{"7"},
+		},
+		{
+			// 第二步请求失败的结果被跳过
+			name: "fetch",
+			definition: config.DeclarativeProvider{Name: "Fetch", Search: search("/search?q={{query}}"), Decode: "base64",
+				Fields: map[string]string{"id": "id", "title": "title"},
+				Fetch:  &config.DeclarativeRequest{URL: server.URL + "/lyric?id={{id}}", Fields: map[string]string{"lyrics": "data.lyric"}}},
+			lids: []string{"7"},
+		},
+		{
+			name: "limit",
+			definition: config.DeclarativeProvider{Name: "Limit", Search: search("/search?q={{query}}"), Limit: 1,
+				Fields: map[string]string{"id": "id", "lyrics": "title"}},
+			lids: []string{"7"},
+		},
+		{
+			name: "missing items",
+			definition: config.DeclarativeProvider{Name: "Missing", Search: config.DeclarativeSearch{
+				DeclarativeRequest: config.DeclarativeRequest{URL: server.URL + "/search?q={{query}}"}, Items: "data.songs",
+			}, Fields: map[string]string{"lyrics": "lrc"}},
+		},
+	}
+	for _, c := range cases {
+		result, err := (Declarative{Definition: c.definition}).Lyrics(request)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		var lids []string
+		for _, relation := range result {
+			lids = append(lids, relation.Lid)
+			if relation.Type != c.definition.Name || relation.Sid != "sid" || lyric.Decode(relation.Lyrics) == "" {
+				t.Errorf("%s: relation = %+v", c.name, relation)
+			}
+		}
+		if strings.Join(lids, ",") != strings.Join(c.lids, ",") {
+			t.Errorf("%s: lids = %v, want %v", c.name, lids, c.lids)
+		}
+	}
+
+	// base64 解码第二步请求返回的歌词
+	fetch := cases[2].definition
+	if result, _ := (Declarative{Definition: fetch}).Lyrics(request); len(result) != 1 || lyric.Decode(result[0].Lyrics) != "[00:01.00]故事的小黄花" || result[0].Name != "晴天" {
+		t.Errorf("fetch: result = %+v", result)
+	}
+
+	// 非 200 和不是 JSON 的响应是搜索失败
+	for _, path := range []string{"/down", "/broken"} {
+		definition := config.DeclarativeProvider{Name: "Error", Search: search(path), Fields: map[string]string{"lyrics": "lrc"}}
+		if _, err := (Declarative{Definition: definition}).Lyrics(request); err == nil {
+			t.Errorf("%s: expected error", path)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	apputils "lyrics/app-utils"
+	"lyrics/lyric"
+	"lyrics/model"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// 两条歌词相同行的比例达到这个值时视为同一份歌词
+const duplicateSimilarity = 0.9
+
+// 计算指纹前去掉署名和空行
+var fingerprintOptions = lyric.CleanOptions{Credits: true, Blank: true}
+
+type candidate struct {
+	relation    model.MusicRelation
+	fingerprint map[string]int
+	lines       int
+	richness    int
+	// 真实的时间轴, 纯文本和估计的时间轴为空
+	timeline []timedLine
+}
+
+// Dedup 合并内容几乎相同且时间轴一致的候选, 保留信息最全的一条 (逐字 > 翻译 > 罗马音 > 逐行) 并列出所有来源, 结果按每组第一次出现的顺序排列.
+// 文字相同但时间轴整体错开的候选不合并, 用户可以选择时间正确的一条
+func Dedup(data []model.MusicRelation) []model.MusicRelation {
+	var groups [][]candidate
+	for _, relation := range data {
+		c := newCandidate(relation)
+		merged := false
+		for i, group := range groups {
+			if c.lines > 0 && similarity(group[0], c) >= duplicateSimilarity && sameTiming(group[0], c) {
+				groups[i] = append(group, c)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			groups = append(groups, []candidate{c})
+		}
+	}
+
+	result := make([]model.MusicRelation, 0, len(groups))
+	for _, group := range groups {
+		best := group[0]
+		for _, c := range group[1:] {
+			if c.richness > best.richness {
+				best = c
+			}
+		}
+		relation := best.relation
+		if len(group) > 1 {
+			relation.Sources = nil
+			seen := map[model.LyricSource]bool{}
+			for _, c := range group {
+				source := model.LyricSource{Type: c.relation.Type, Lid: c.relation.Lid}
+				if !seen[source] {
+					seen[source] = true
+					relation.Sources = append(relation.Sources, source)
+				}
+			}
+		}
+		result = append(result, relation)
+	}
+	return result
+}
+
+func newCandidate(relation model.MusicRelation) candidate {
+	c := candidate{relation: relation, fingerprint: map[string]int{}}
+	parsed := lyric.Parse(lyric.Clean(lyric.Decode(relation.Lyrics), fingerprintOptions, nil))
+	for _, line := range parsed.Lines {
+		if text := normalizeLine(line.Text); text != "" {
+			c.fingerprint[text]++
+			c.lines++
+		}
+		if len(line.Words) > 0 {
+			c.richness |= 8
+		}
+		// 估计的时间轴不算逐行
+		if line.Start > 0 && !relation.Estimated {
+			c.richness |= 1
+		}
+	}
+	if !relation.Estimated {
+		c.timeline = timeline(relation)
+	}
+	if strings.TrimSpace(lyric.Decode(relation.Trans)) != "" {
+		c.richness |= 4
+	}
+	if strings.TrimSpace(lyric.Decode(relation.Roma)) != "" {
+		c.richness |= 2
+	}
+	return c
+}
+
+// normalizeLine 转为简体和小写, 只保留文字和数字
+func normalizeLine(text string) string {
+	if simplified, err := apputils.T2s(text); err == nil {
+		text = simplified
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// sameTiming 两边都有真实的时间轴时, 相同行的时间差中位数不超过 consensusTolerance 才算同一份歌词;
+// 纯文本和估计的时间轴只按文字合并
+func sameTiming(a candidate, b candidate) bool {
+	if a.timeline == nil || b.timeline == nil {
+		return true
+	}
+	deltas, _ := matchLines(a.timeline, b.timeline)
+	if len(deltas) == 0 {
+		return false
+	}
+	slices.Sort(deltas)
+	return abs(deltas[len(deltas)/2]) <= consensusTolerance
+}
+
+// similarity 相同行 (按出现次数) 占两边总行数的比例
+func similarity(a candidate, b candidate) float64 {
+	if a.lines == 0 || b.lines == 0 {
+		return 0
+	}
+	common := 0
+	for text, n := range a.fingerprint {
+		common += min(n, b.fingerprint[text])
+	}
+	return 2 * float64(common) / float64(a.lines+b.lines)
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"encoding/base64"
+	"lyrics/lyric"
+	"lyrics/model"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	var data []model.MusicRelation
+	for _, p := range []Provider{QQMusicLyrics{}, QQMusicLK{}, NetEaseMusic{}, NetEaseLK{}, KugouMusic{}, KugouLK{}, LRCLIB{}} {
+		data = append(data, search(t, p)...)
+	}
+	result := Dedup(data)
+	if len(result) != 1 {
+		t.Fatalf("results = %d, want 1", len(result))
+	}
+	// 逐字 + 翻译 + 罗马音
+	if result[0].Type != QQMusicLKType {
+		t.Errorf("representative = %q, want %q", result[0].Type, QQMusicLKType)
+	}
+	if len(result[0].Sources) != 7 {
+		t.Errorf("sources = %+v, want 7", result[0].Sources)
+	}
+
+	// 不同的歌不合并; 信息一样多时保留先出现的一条
+	other := []string{"窗外的麻雀", "在电线杆上多嘴", "你说这一句", "很有夏天的感觉", "手中的铅笔", "在纸上来来回回"}
+	first := syntheticRelation("first", syntheticTexts, 0)
+	first.Trans = first.Lyrics
+	second := syntheticRelation("second", syntheticTexts, 0)
+	second.Trans = second.Lyrics
+	roma := syntheticRelation("roma", syntheticTexts, 0)
+	roma.Roma = roma.Lyrics
+	result = Dedup([]model.MusicRelation{syntheticRelation("plain", syntheticTexts, 0), syntheticRelation("other", other, 0), first, roma, second})
+	if len(result) != 2 {
+		t.Fatalf("results = %d, want 2", len(result))
+	}
+	if result[0].Lid != "first" || len(result[0].Sources) != 4 {
+		t.Errorf("representative = %q with %d sources, want first with 4", result[0].Lid, len(result[0].Sources))
+	}
+	if result[1].Lid != "other" || len(result[1].Sources) != 0 {
+		t.Errorf("second group = %q with %+v, want other alone", result[1].Lid, result[1].Sources)
+	}
+
+	// 文字相同但时间轴错开几秒的不合并; 估计的时间轴只按文字合并
+	shifted := syntheticRelation("shifted", syntheticTexts, 3000)
+	shifted.Trans = shifted.Lyrics
+	estimated := syntheticRelation("estimated", syntheticTexts, 8000)
+	estimated.Estimated = true
+	result = Dedup([]model.MusicRelation{syntheticRelation("plain", syntheticTexts, 100), shifted, estimated})
+	if len(result) != 2 || result[0].Lid != "plain" || result[1].Lid != "shifted" {
+		t.Fatalf("results = %+v, want plain and shifted", result)
+	}
+	if len(result[0].Sources) != 2 || result[0].Sources[1].Lid != "estimated" {
+		t.Errorf("sources = %+v, want plain and estimated", result[0].Sources)
+	}
+}
+
+var syntheticTexts = []string{"故事的小黄花", "从出生那年就飘着", "童年的荡秋千", "随记忆一直晃到现在", "吹着前奏望着天空", "我想起花瓣试着掉落"}
+
+// syntheticRelation 每 4 秒一行的逐行歌词, 整体平移 shift (ms)
+func syntheticRelation(lid string, texts []string, shift int64) model.MusicRelation {
+	var content lyric.Lyric
+	for j, text := range texts {
+		start := int64(j*4000) + 1000 + shift
+		content.Lines = append(content.Lines, lyric.Line{Start: start, End: start + 3500, Text: text})
+	}
+	return model.MusicRelation{Lid: lid, Lyrics: base64.StdEncoding.EncodeToString([]byte(lyric.Format(content)))}
+}
@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"lyrics/config"
+	"lyrics/model"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+
+	// 计算耗时分位数保留的最近调用数
+	healthSamples = 200
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Guarded 记录 Provider 的健康状况, 连续失败后熔断, 冷却结束后只放行一次探测请求
+type Guarded struct {
+	Provider Provider
+
+	name    string
+	breaker config.BreakerConfig
+
+	mu          sync.Mutex
+	state       string
+	probing     bool
+	calls       int64
+	failures    int64
+	skipped     int64
+	consecutive int
+	latencies   []int64
+	next        int
+	lastError   string
+	lastErrorAt time.Time
+	retryAt     time.Time
+	// 熔断计时使用的时钟, 测试时替换
+	now func() time.Time
+}
+
+func Guard(providers []Provider, breaker config.BreakerConfig) []*Guarded {
+	var result []*Guarded
+	for _, p := range providers {
+		result = append(result, &Guarded{Provider: p, name: providerName(p), breaker: breaker, state: circuitClosed, now: time.Now})
+	}
+	return result
+}
+
+// providerName 声明式 Provider 和插件使用配置的名字, 其余使用类型名
+func providerName(p Provider) string {
+	if named, ok := p.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	t := reflect.TypeOf(p)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+func (g *Guarded) Name() string {
+	return g.name
+}
+
+func (g *Guarded) Lyrics(request model.SearchRequest) (result []model.MusicRelation, err error) {
+	if !g.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic: %v", r)
+		}
+		g.record(time.Since(start), err)
+	}()
+	return g.Provider.Lyrics(request)
+}
+
+func (g *Guarded) allow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.state {
+	case circuitOpen:
+		if g.now().Before(g.retryAt) {
+			g.skipped++
+			return false
+		}
+		g.state = circuitHalfOpen
+		g.probing = true
+		return true
+	case circuitHalfOpen:
+		if g.probing {
+			g.skipped++
+			return false
+		}
+		g.probing = true
+	}
+	return true
+}
+
+func (g *Guarded) record(latency time.Duration, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.calls++
+	if len(g.latencies) < healthSamples {
+		g.latencies = append(g.latencies, latency.Milliseconds())
+	} else {
+		g.latencies[g.next] = latency.Milliseconds()
+	}
+	g.next = (g.next + 1) % healthSamples
+	g.probing = false
+
+	if err == nil {
+		if g.state != circuitClosed {
+			log.Printf("[INFO] Provider %s Recovered", g.name)
+		}
+		g.state = circuitClosed
+		g.consecutive = 0
+		return
+	}
+
+	g.failures++
+	g.consecutive++
+	g.lastError = err.Error()
+	g.lastErrorAt = g.now()
+	log.Printf("[ERROR] Provider %s Failed: %s", g.name, err)
+
+	if g.state == circuitHalfOpen || (g.breaker.Failures > 0 && g.consecutive >= g.breaker.Failures) {
+		g.state = circuitOpen
+		g.retryAt = g.now().Add(g.breaker.Cooldown)
+		log.Printf("[ERROR] Provider %s Circuit Open Until %s", g.name, g.retryAt.Format(time.RFC3339))
+	}
+}
+
+func (g *Guarded) Health() model.ProviderHealth {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	health := model.ProviderHealth{
+		Name:                g.name,
+		State:               g.state,
+		Calls:               g.calls,
+		Failures:            g.failures,
+		Skipped:             g.skipped,
+		SuccessRate:         1,
+		ConsecutiveFailures: g.consecutive,
+		LastError:           g.lastError,
+	}
+	if g.calls > 0 {
+		health.SuccessRate = float64(g.calls-g.failures) / float64(g.calls)
+	}
+	if !g.lastErrorAt.IsZero() {
+		health.LastErrorAt = g.lastErrorAt.Format(time.RFC3339)
+	}
+	if g.state == circuitOpen {
+		health.RetryAt = g.retryAt.Format(time.RFC3339)
+	}
+
+	latencies := append([]int64(nil), g.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	health.LatencyP50 = percentile(latencies, 0.5)
+	health.LatencyP90 = percentile(latencies, 0.9)
+	health.LatencyP99 = percentile(latencies, 0.99)
+	return health
+}
+
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
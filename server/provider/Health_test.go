@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"errors"
+	"lyrics/config"
+	"lyrics/model"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	lyrics func(request model.SearchRequest) ([]model.MusicRelation, error)
+}
+
+func (p *stubProvider) Name() string {
+	return "Stub"
+}
+
+func (p *stubProvider) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
+	return p.lyrics(request)
+}
+
+func TestBreaker(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	failing := errors.New("upstream down")
+	var err error
+	stub := &stubProvider{}
+	stub.lyrics = func(model.SearchRequest) ([]model.MusicRelation, error) { return nil, err }
+
+	g := Guard([]Provider{stub}, config.BreakerConfig{Failures: 2, Cooldown: time.Minute})[0]
+	g.now = func() time.Time { return clock }
+
+	state := func(step, want string) {
+		t.Helper()
+		if health := g.Health(); health.State != want {
+			t.Fatalf("%s: state = %s, want %s", step, health.State, want)
+		}
+	}
+
+	err = failing
+	g.Lyrics(model.SearchRequest{})
+	state("one failure", circuitClosed)
+	g.Lyrics(model.SearchRequest{})
+	state("two failures", circuitOpen)
+	if health := g.Health(); health.RetryAt != clock.Add(time.Minute).Format(time.RFC3339) || health.ConsecutiveFailures != 2 {
+		t.Fatalf("open: health = %+v", health)
+	}
+
+	// 冷却期间直接跳过, 不调用上游
+	err = nil
+	clock = clock.Add(59 * time.Second)
+	if _, e := g.Lyrics(model.SearchRequest{}); !errors.Is(e, ErrCircuitOpen) {
+		t.Fatalf("cooldown: err = %v, want ErrCircuitOpen", e)
+	}
+	state("cooldown", circuitOpen)
+
+	// 冷却结束只放行一次探测, 探测进行中的其他请求跳过
+	clock = clock.Add(time.Second)
+	var nested error
+	stub.lyrics = func(model.SearchRequest) ([]model.MusicRelation, error) {
+		state("probe", circuitHalfOpen)
+		_, nested = g.Lyrics(model.SearchRequest{})
+		return nil, err
+	}
+	err = failing
+	g.Lyrics(model.SearchRequest{})
+	if !errors.Is(nested, ErrCircuitOpen) {
+		t.Fatalf("half open: nested err = %v, want ErrCircuitOpen", nested)
+	}
+	// 探测失败重新熔断, 从当前时间开始冷却
+	state("failed probe", circuitOpen)
+	if health := g.Health(); health.RetryAt != clock.Add(time.Minute).Format(time.RFC3339) {
+		t.Fatalf("failed probe: retry_at = %s", health.RetryAt)
+	}
+
+	clock = clock.Add(time.Minute)
+	stub.lyrics = func(model.SearchRequest) ([]model.MusicRelation, error) { return nil, nil }
+	if _, e := g.Lyrics(model.SearchRequest{}); e != nil {
+		t.Fatalf("recovered probe: err = %v", e)
+	}
+	state("recovered", circuitClosed)
+	health := g.Health()
+	if health.ConsecutiveFailures != 0 || health.RetryAt != "" {
+		t.Fatalf("recovered: health = %+v", health)
+	}
+	// 跳过的请求不计入调用次数
+	if health.Calls != 4 || health.Failures != 3 || health.Skipped != 2 {
+		t.Errorf("counters = calls %d failures %d skipped %d, want 4 3 2", health.Calls, health.Failures, health.Skipped)
+	}
+}
+
+func TestBreakerPanic(t *testing.T) {
+	stub := &stubProvider{lyrics: func(model.SearchRequest) ([]model.MusicRelation, error) { panic("boom") }}
+	g := Guard([]Provider{stub}, config.BreakerConfig{Failures: 1, Cooldown: time.Minute})[0]
+
+	if _, err := g.Lyrics(model.SearchRequest{}); err == nil || err.Error() != "panic: boom" {
+		t.Fatalf("err = %v, want panic: boom", err)
+	}
+	if health := g.Health(); health.State != circuitOpen || health.LastError != "panic: boom" {
+		t.Errorf("health = %+v", health)
+	}
+}
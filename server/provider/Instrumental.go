@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"lyrics/lyric"
+	"lyrics/model"
+	"regexp"
+	"strings"
+)
+
+// 纯音乐的占位文字
+var instrumentalPlaceholder = regexp.MustCompile(`(?i)^(纯音乐|純音樂|此歌曲为没有填词的纯音乐|此歌曲為沒有填詞的純音樂|instrumental\b)`)
+
+// 没有歌词的占位文字
+var emptyPlaceholder = regexp.MustCompile(`^(暂无歌词|暫無歌詞|暂时没有歌词|歌词未找到|无歌词|無歌詞)`)
+
+// 占位歌词最多的行数, 超过时认为是正常歌词
+const placeholderLines = 3
+
+// Placeholder 判断候选是不是占位内容: 纯音乐标记 / 纯音乐占位文字返回 instrumental, 暂无歌词返回 not_found
+func Placeholder(relation model.MusicRelation) (string, bool) {
+	if relation.Instrumental {
+		return model.StatusInstrumental, true
+	}
+	content := lyric.Clean(lyric.Decode(relation.Lyrics), fingerprintOptions, nil)
+	var texts []string
+	for _, line := range lyric.Parse(content).Lines {
+		texts = append(texts, line.Text)
+	}
+	// 纯文本歌词按行检查
+	if len(texts) == 0 && lyric.Plain(content) {
+		texts = strings.Split(content, "\n")
+	}
+	status := ""
+	lines := 0
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		lines++
+		switch {
+		case instrumentalPlaceholder.MatchString(text):
+			status = model.StatusInstrumental
+		case emptyPlaceholder.MatchString(text):
+			if status == "" {
+				status = model.StatusNotFound
+			}
+		default:
+			return "", false
+		}
+	}
+	if lines == 0 {
+		return model.StatusNotFound, true
+	}
+	return status, lines <= placeholderLines
+}
@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"encoding/base64"
+	"lyrics/model"
+	"testing"
+)
+
+func TestPlaceholder(t *testing.T) {
+	encode := func(content string) string {
+		return base64.StdEncoding.EncodeToString([]byte(content))
+	}
+	cases := []struct {
+		name     string
+		relation model.MusicRelation
+		status   string
+		ok       bool
+	}{
+		{"instrumental text", model.MusicRelation{Lyrics: encode("[00:00.00]纯音乐，请欣赏")}, model.StatusInstrumental, true},
+		{"instrumental with credits", model.MusicRelation{Lyrics: encode("[00:00.00]作曲 : 某人\n[00:01.00]此歌曲为没有填词的纯音乐，请您欣赏")}, model.StatusInstrumental, true},
+		{"no lyrics text", model.MusicRelation{Lyrics: encode("[00:00.00]暂无歌词")}, model.StatusNotFound, true},
+		{"plain placeholder", model.MusicRelation{Lyrics: encode("纯音乐，请欣赏")}, model.StatusInstrumental, true},
+		{"empty", model.MusicRelation{}, model.StatusNotFound, true},
+		{"instrumental flag", model.MusicRelation{Instrumental: true, Lyrics: encode("[00:01.00]啦啦啦")}, model.StatusInstrumental, true},
+		// 很短的正常歌词不是占位
+		{"short lyrics", model.MusicRelation{Lyrics: encode("[00:01.00]啦啦啦")}, "", false},
+		{"short plain lyrics", model.MusicRelation{Lyrics: encode("Instrumentally yours")}, "", false},
+		{"placeholder mixed with lyrics", model.MusicRelation{Lyrics: encode("[00:00.00]纯音乐\n[00:05.00]但这一行是歌词")}, "", false},
+	}
+	for _, c := range cases {
+		status, ok := Placeholder(c.relation)
+		if status != c.status || ok != c.ok {
+			t.Errorf("%s: Placeholder = %q, %v, want %q, %v", c.name, status, ok, c.status, c.ok)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	app_utils "lyrics/app-utils"
@@ -15,22 +16,24 @@ import (
 type KugouMusic struct {
 }
 
+var kugouClient = app_utils.Client("KugouMusic")
+
 var kugouSearch = "http://mobilecdn.kugou.com/api/v3/search/song?format=json&keyword=%s&page=1&pagesize=10&showtype=1"
 var kugouMusicDetail = "http://krcs.kugou.com/search?ver=1&man=yes&client=mobi&hash=%s"
-var kugouLyricsBaseUrl = "http://lyrics.kugou.com/download?ver=1&client=pc&id=%s&accesskey=%s&fmt=krc&charset=utf8"
+var kugouLyricsBaseUrl = "http://lyrics.kugou.com/download?ver=1&client=pc&id=%s&accesskey=%s&fmt=%s&charset=utf8"
 
-func (search KugouMusic) Lyrics(request model.SearchRequest) []model.MusicRelation {
+func (search KugouMusic) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
 	var result []model.MusicRelation
 
-	data, done := search.search(request.Name + " " + request.Singer)
-	if done {
-		return result
+	data, err := search.search(request.Name + " " + request.Singer)
+	if err != nil {
+		return result, err
 	}
 
 	if len(data.Data.Info) < 1 {
-		data, done = search.search(request.Name)
-		if done {
-			return result
+		data, err = search.search(request.Name)
+		if err != nil {
+			return result, err
 		}
 		if len(data.Data.Info) < 1 {
 			split := []string{"(", "（", "-", "《"}
@@ -41,9 +44,12 @@ func (search KugouMusic) Lyrics(request model.SearchRequest) []model.MusicRelati
 				}
 			}
 			if minIndex == len(request.Name) {
-				return result
+				return result, nil
+			}
+			data, err = search.search(request.Name[:minIndex])
+			if err != nil {
+				return result, err
 			}
-			data, done = search.search(request.Name[:minIndex])
 		}
 	}
 
@@ -54,7 +60,7 @@ func (search KugouMusic) Lyrics(request model.SearchRequest) []model.MusicRelati
 			continue
 		}
 		for _, song := range detail.Candidates {
-			lyrics, err := search.lyrics(song.Id, song.Accesskey)
+			lyrics, err := search.lyrics(song.Id, song.Accesskey, request.LineOnly)
 			if err != nil {
 				log.Println(fmt.Sprintf("[ERROR] search lyrics [%s,%s,%s,%s]", song.Id, song.Accesskey, song.Song, info.Hash), err)
 				continue
@@ -65,41 +71,39 @@ func (search KugouMusic) Lyrics(request model.SearchRequest) []model.MusicRelati
 				Lid:    fmt.Sprintf("%s-%s-%s", info.Hash, song.Id, song.Accesskey),
 				Sid:    request.Id,
 				// 获取歌词
-				Lyrics: lyrics,
+				Lyrics: base64.StdEncoding.EncodeToString([]byte(lyrics)),
 				Type:   KuGou,
 				Offset: 0,
 			})
 		}
 
 	}
-	return result
+	return result, nil
 }
 
-func (search KugouMusic) search(source string) (model.KugouSearchModel, bool) {
+func (search KugouMusic) search(source string) (model.KugouSearchModel, error) {
 	key, err := app_utils.T2s(source)
 	log.Printf("[INFO] T2s Res " + key)
 	if err != nil {
 		log.Printf(fmt.Sprintf("[ERROR] T2s Failed [%s] %v", source, err))
 		key = source
 	}
-	data, err := app_utils.HttpGet[model.KugouSearchModel](fmt.Sprintf(kugouSearch, url.QueryEscape(key)), map[string]string{})
+	data, err := app_utils.HttpGet[model.KugouSearchModel](kugouClient, fmt.Sprintf(kugouSearch, url.QueryEscape(key)), map[string]string{})
 	if err != nil {
-		log.Printf("[ERROR] Failed GET Kugou Music Response!")
-		return model.KugouSearchModel{}, true
+		return model.KugouSearchModel{}, fmt.Errorf("failed GET Kugou Music search: %w", err)
 	}
 
 	if data.Errcode != 0 {
-		log.Printf("Search Kugou API Error Code " + strconv.Itoa(data.Status) + " Subcode " + data.Error)
-		return model.KugouSearchModel{}, true
+		return model.KugouSearchModel{}, errors.New("Search Kugou API Error Code " + strconv.Itoa(data.Status) + " Subcode " + data.Error)
 	}
-	return data, false
+	return data, nil
 }
 
 func (search KugouMusic) detail(hash string) (model.KugouDetailModel, error) {
 	var detail model.KugouDetailModel
-	detail, err := app_utils.HttpGet[model.KugouDetailModel](fmt.Sprintf(kugouMusicDetail, hash), map[string]string{})
+	detail, err := app_utils.HttpGet[model.KugouDetailModel](kugouClient, fmt.Sprintf(kugouMusicDetail, hash), map[string]string{})
 	if err != nil {
-		return detail, errors.New(fmt.Sprintf("[ERROR] Failed Get Kugou Detail [%s - %s]: %s", hash, lyricsBaseUrl, err))
+		return detail, errors.New(fmt.Sprintf("[ERROR] Failed Get Kugou Detail [%s - %s]: %s", hash, kugouMusicDetail, err))
 	}
 	if detail.Status != 200 {
 		return detail, errors.New(fmt.Sprintf("[ERROR] Failed to get Kugou Detail for Music [%s - %s]", hash, detail.Errmsg))
@@ -107,14 +111,29 @@ func (search KugouMusic) detail(hash string) (model.KugouDetailModel, error) {
 	return detail, nil
 }
 
-func (search KugouMusic) lyrics(id string, accesskey string) (string, error) {
-	lyrics, err := app_utils.HttpGet[model.KugouLyricsModel](fmt.Sprintf(kugouLyricsBaseUrl, id, accesskey), map[string]string{})
+// lyrics 下载并解密歌词, 不需要逐字时间时直接下载 LRC
+func (search KugouMusic) lyrics(id string, accesskey string, lineOnly bool) (string, error) {
+	format := "krc"
+	if lineOnly {
+		format = "lrc"
+	}
+	lyrics, err := app_utils.HttpGet[model.KugouLyricsModel](kugouClient, fmt.Sprintf(kugouLyricsBaseUrl, id, accesskey, format), map[string]string{})
 	if err != nil {
-		return lyrics.Content, errors.New(fmt.Sprintf("[ERROR] Failed Get Kugou Lyrics [%s-%s - %s]: %s", id, accesskey, lyricsBaseUrl, err))
+		return "", errors.New(fmt.Sprintf("[ERROR] Failed Get Kugou Lyrics [%s-%s - %s]: %s", id, accesskey, kugouLyricsBaseUrl, err))
 	}
 	if lyrics.Status != 200 {
-		return lyrics.Content, errors.New(fmt.Sprintf("[ERROR] Failed to get Kugou lyrics for Music [%s-%s - %s]", id, accesskey, lyrics.Content))
+		return "", errors.New(fmt.Sprintf("[ERROR] Failed to get Kugou lyrics for Music [%s-%s - %s]", id, accesskey, lyrics.Info))
+	}
+	content, err := base64.StdEncoding.DecodeString(lyrics.Content)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("[ERROR] Failed Decode Kugou Lyrics [%s-%s]: %s", id, accesskey, err))
+	}
+	if lyrics.Fmt != "krc" {
+		return string(content), nil
+	}
+	decrypted, err := app_utils.DecryptKugouKrc(content)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("[ERROR] Failed Decrypt Kugou KRC [%s-%s]: %s", id, accesskey, err))
 	}
-	log.Printf("[INFO] KuGou Decode by %s", lyrics.Fmt)
-	return lyrics.Content, nil
+	return decrypted, nil
 }
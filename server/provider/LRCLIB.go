@@ -1,48 +1,224 @@
 package provider
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	apputils "lyrics/app-utils"
+	"lyrics/lyric"
 	"lyrics/model"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type LRCLIB struct{}
+var lrclibDefaultURL = "https://lrclib.net"
 
-func (l LRCLIB) Lyrics(request model.SearchRequest) []model.MusicRelation {
+var lrclibClient = apputils.Client("LRCLIB")
+
+// LRCLIB BaseURL 为空时使用 lrclib.net, 也可以指向兼容的自建服务
+type LRCLIB struct {
+	BaseURL string
+}
+
+func (l LRCLIB) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
 	var result []model.MusicRelation
 
+	// 有时长时先按签名精确查找, 命中即为权威结果
+	if request.Duration > 0 && request.Name != "" && request.Singer != "" {
+		item, err := l.get(request)
+		if err == nil {
+			if item.Instrumental {
+				log.Printf("[INFO] LRCLIB Instrumental [%s - %s]", request.Name, request.Singer)
+				return append(result, model.MusicRelation{
+					Name:         item.TrackName,
+					Singer:       item.ArtistName,
+					Lid:          fmt.Sprintf("%d", item.ID),
+					Sid:          request.Id,
+					Type:         LRCLIBType,
+					Instrumental: true,
+				}), nil
+			}
+			if relation, ok := l.relation(item, request); ok {
+				return append(result, relation), nil
+			}
+		} else {
+			log.Printf("[INFO] LRCLIB Exact Lookup Missed [%s - %s]: %v", request.Name, request.Singer, err)
+		}
+	}
+
 	query := request.Name + " " + request.Singer
-	searchURL := fmt.Sprintf("https://lrclib.net/api/search?q=%s", url.QueryEscape(query))
+	searchURL := fmt.Sprintf("%s/api/search?q=%s", l.base(), url.QueryEscape(query))
 
-	responses, err := apputils.HttpGet[[]model.LRCLIBResponse](searchURL, nil)
+	responses, err := apputils.HttpGet[[]model.LRCLIBResponse](lrclibClient, searchURL, nil)
 	if err != nil {
-		log.Printf("[ERROR] Failed to query LRCLIB: %v", err)
-		return result
+		return result, fmt.Errorf("failed to query LRCLIB: %w", err)
 	}
 
 	for _, item := range responses {
-		lyrics := item.SyncedLyrics
-		if lyrics == "" {
-			lyrics = item.PlainLyrics
-		}
-		if lyrics == "" {
-			continue
+		if relation, ok := l.relation(item, request); ok {
+			result = append(result, relation)
 		}
+	}
+
+	return result, nil
+}
+
+func (l LRCLIB) get(request model.SearchRequest) (model.LRCLIBResponse, error) {
+	params := url.Values{}
+	params.Set("track_name", request.Name)
+	params.Set("artist_name", request.Singer)
+	if request.Album != "" {
+		params.Set("album_name", request.Album)
+	}
+	params.Set("duration", strconv.FormatInt((request.Duration+500)/1000, 10))
+	return apputils.HttpGet[model.LRCLIBResponse](lrclibClient, l.base()+"/api/get?"+params.Encode(), nil)
+}
 
-		result = append(result, model.MusicRelation{
-			Name:   item.TrackName,
-			Singer: item.ArtistName,
-			Lid:    fmt.Sprintf("%d", item.ID),
-			Sid:    request.Id,
-			Lyrics: base64.StdEncoding.EncodeToString([]byte(lyrics)),
-			Trans:  "",
-			Type:   LRCLIBType,
-			Offset: 0,
-		})
+func (l LRCLIB) relation(item model.LRCLIBResponse, request model.SearchRequest) (model.MusicRelation, bool) {
+	lyrics := item.SyncedLyrics
+	if lyrics == "" {
+		lyrics = item.PlainLyrics
+	}
+	if lyrics == "" {
+		return model.MusicRelation{}, false
+	}
+
+	return model.MusicRelation{
+		Name:   item.TrackName,
+		Singer: item.ArtistName,
+		Lid:    fmt.Sprintf("%d", item.ID),
+		Sid:    request.Id,
+		Lyrics: base64.StdEncoding.EncodeToString([]byte(lyrics)),
+		Trans:  "",
+		Type:   LRCLIBType,
+		Offset: 0,
+	}, true
+}
+
+var publishOptions = lyric.CleanOptions{Credits: true, Blank: true}
+
+// Publish 发布逐行歌词, 先完成 LRCLIB 的工作量证明拿到发布 token; ctx 取消时停止计算和请求
+func (l LRCLIB) Publish(ctx context.Context, request model.PublishRequest) error {
+	if request.Name == "" || request.Singer == "" || request.Album == "" || request.Duration <= 0 {
+		return errors.New("name, singer, album and duration are required")
+	}
+	// 开头的署名行不是演唱的歌词, 不发布
+	parsed := lyric.Parse(lyric.Clean(lyric.Decode(request.Lyrics), publishOptions, nil))
+	if len(parsed.Lines) == 0 {
+		return errors.New("no synced lyrics to publish")
 	}
 
-	return result
+	var plain []string
+	synced := lyric.Lyric{}
+	for _, line := range parsed.Lines {
+		plain = append(plain, line.Text)
+		// 歌词时间 = 播放进度 + offset, 发布的是按播放进度校正后的时间
+		synced.Lines = append(synced.Lines, lyric.Line{Start: max(line.Start-request.Offset, 0), Text: line.Text})
+	}
+	body := model.LRCLIBPublish{
+		TrackName:    request.Name,
+		ArtistName:   request.Singer,
+		AlbumName:    request.Album,
+		Duration:     float64(request.Duration) / 1000,
+		PlainLyrics:  strings.Join(plain, "\n"),
+		SyncedLyrics: lyric.Format(synced),
+	}
+
+	var challenge model.LRCLIBChallenge
+	status, content, err := l.post(ctx, "/api/request-challenge", struct{}{}, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("LRCLIB challenge response %d: %s", status, content)
+	}
+	if err := json.Unmarshal(content, &challenge); err != nil {
+		return fmt.Errorf("invalid LRCLIB challenge %q", content)
+	}
+	nonce, err := solveChallenge(ctx, challenge)
+	if err != nil {
+		return err
+	}
+
+	status, content, err = l.post(ctx, "/api/publish", body, map[string]string{"X-Publish-Token": challenge.Prefix + ":" + nonce})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusCreated && status != http.StatusOK {
+		return fmt.Errorf("LRCLIB publish response %d: %s", status, content)
+	}
+	log.Printf("[INFO] Published To LRCLIB [%s - %s]", request.Name, request.Singer)
+	return nil
+}
+
+// post 发送 JSON, 返回状态码和最多 1KB 的响应内容
+func (l LRCLIB) post(ctx context.Context, path string, body any, headers map[string]string) (int, []byte, error) {
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", l.base()+path, bytes.NewBuffer(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := lrclibClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return resp.StatusCode, content, err
+}
+
+const (
+	// 每计算这么多次检查一次 ctx 和时间
+	challengeCheck = 1 << 16
+	// 工作量证明最多计算的时间和次数, lrclib.net 的难度一般几秒到几十秒
+	challengeBudget = 2 * time.Minute
+	maxChallenge    = 1 << 32
+)
+
+// solveChallenge 寻找 nonce 使 sha256(prefix + nonce) <= target, 超过时间 / 次数或 ctx 取消时返回错误
+func solveChallenge(ctx context.Context, challenge model.LRCLIBChallenge) (string, error) {
+	target, err := hex.DecodeString(challenge.Target)
+	if err != nil || len(target) != sha256.Size {
+		return "", fmt.Errorf("invalid LRCLIB challenge target %q", challenge.Target)
+	}
+	deadline := time.Now().Add(challengeBudget)
+	for nonce := 0; nonce < maxChallenge; nonce++ {
+		if nonce%challengeCheck == 0 && nonce > 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			if time.Now().After(deadline) {
+				return "", fmt.Errorf("LRCLIB challenge not solved in %s", challengeBudget)
+			}
+		}
+		n := strconv.Itoa(nonce)
+		sum := sha256.Sum256([]byte(challenge.Prefix + n))
+		if bytes.Compare(sum[:], target) <= 0 {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("LRCLIB challenge not solved in %d attempts", maxChallenge)
+}
+
+func (l LRCLIB) base() string {
+	if l.BaseURL == "" {
+		return lrclibDefaultURL
+	}
+	return strings.TrimSuffix(l.BaseURL, "/")
 }
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"lyrics/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 第一个字节为 0 即可, 平均 256 次
+const easyTarget = "00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+
+var publishRequest = model.PublishRequest{
+	Name:     "晴天",
+	Singer:   "周杰伦",
+	Album:    "叶惠美",
+	Duration: 269000,
+	Lyrics:   base64.StdEncoding.EncodeToString([]byte("[00:01.00]故事的小黄花\n[00:05.50]从出生那年就飘着")),
+}
+
+// publishStandIn 模拟 LRCLIB 的 request-challenge 和 publish, 检查发布 token 的工作量证明
+func publishStandIn(t *testing.T, challengeStatus int, publishStatus int) (*httptest.Server, *model.LRCLIBPublish) {
+	t.Helper()
+	published := &model.LRCLIBPublish{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/request-challenge":
+			w.WriteHeader(challengeStatus)
+			_ = json.NewEncoder(w).Encode(model.LRCLIBChallenge{Prefix: "prefix", Target: easyTarget})
+		case "/api/publish":
+			prefix, nonce, _ := strings.Cut(r.Header.Get("X-Publish-Token"), ":")
+			sum := sha256.Sum256([]byte(prefix + nonce))
+			target, _ := hex.DecodeString(easyTarget)
+			if prefix != "prefix" || bytes.Compare(sum[:], target) > 0 {
+				http.Error(w, "invalid publish token", http.StatusBadRequest)
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(published); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(publishStatus)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, published
+}
+
+func TestPublish(t *testing.T) {
+	server, published := publishStandIn(t, http.StatusOK, http.StatusCreated)
+	if err := (LRCLIB{BaseURL: server.URL}).Publish(context.Background(), publishRequest); err != nil {
+		t.Fatalf("Publish: %s", err)
+	}
+	if published.TrackName != "晴天" || published.Duration != 269 {
+		t.Errorf("published = %+v", *published)
+	}
+	if published.PlainLyrics != "故事的小黄花\n从出生那年就飘着" {
+		t.Errorf("plain = %q", published.PlainLyrics)
+	}
+	if !strings.Contains(published.SyncedLyrics, "[00:05.50]从出生那年就飘着") {
+		t.Errorf("synced = %q", published.SyncedLyrics)
+	}
+}
+
+// 开头的署名行不发布, 每一行按 offset 校正 (歌词时间 = 播放进度 + offset)
+func TestPublishOffsetAndCredits(t *testing.T) {
+	server, published := publishStandIn(t, http.StatusOK, http.StatusCreated)
+	request := publishRequest
+	request.Lyrics = base64.StdEncoding.EncodeToString([]byte("[00:00.00]作词 : 方文山\n[00:01.00]故事的小黄花\n[00:05.50]从出生那年就飘着"))
+	request.Offset = 500
+	if err := (LRCLIB{BaseURL: server.URL}).Publish(context.Background(), request); err != nil {
+		t.Fatalf("Publish: %s", err)
+	}
+	if published.PlainLyrics != "故事的小黄花\n从出生那年就飘着" {
+		t.Errorf("plain = %q", published.PlainLyrics)
+	}
+	if !strings.HasPrefix(published.SyncedLyrics, "[00:00.50]故事的小黄花\n[00:05.00]从出生那年就飘着") {
+		t.Errorf("synced = %q", published.SyncedLyrics)
+	}
+}
+
+func TestPublishErrors(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		challenge int
+		publish   int
+		want      string
+	}{
+		{"challenge failed", http.StatusServiceUnavailable, http.StatusCreated, "challenge response 503"},
+		{"publish rejected", http.StatusOK, http.StatusConflict, "publish response 409"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			server, _ := publishStandIn(t, c.challenge, c.publish)
+			err := (LRCLIB{BaseURL: server.URL}).Publish(context.Background(), publishRequest)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("err = %v, want %q", err, c.want)
+			}
+		})
+	}
+
+	request := publishRequest
+	request.Album = ""
+	if err := (LRCLIB{BaseURL: "http://127.0.0.1:1"}).Publish(context.Background(), request); err == nil {
+		t.Error("expected error without album")
+	}
+}
+
+func TestSolveChallenge(t *testing.T) {
+	if _, err := solveChallenge(context.Background(), model.LRCLIBChallenge{Prefix: "p", Target: "zz"}); err == nil {
+		t.Error("expected error for invalid target")
+	}
+
+	// 全 0 的目标不可能满足, 必须在 ctx 超时后返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := solveChallenge(ctx, model.LRCLIBChallenge{Prefix: "p", Target: strings.Repeat("00", sha256.Size)})
+	if err == nil {
+		t.Fatal("expected error for impossible target")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("solveChallenge returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"lyrics/lyric"
+	"lyrics/model"
+	"slices"
+	"strings"
+)
+
+// Languages 识别每个候选歌词和翻译的语言, 标记其实是翻译的候选, 并按期望的语言排序.
+// 原文语言按候选投票决定, 其他候选的翻译语言会抵消票数, 票数相同时取 preferred 中靠前的语言.
+// 排序: preferred 中的语言 (按顺序), 其他语言 (原文语言在前), 最后是翻译和罗马音
+func Languages(data []model.MusicRelation, preferred []string) []model.MusicRelation {
+	votes := map[string]int{}
+	var order []string
+	for i := range data {
+		data[i].Language = lyric.Detect(lyric.Decode(data[i].Lyrics))
+		data[i].TransLanguage = lyric.Detect(lyric.Decode(data[i].Trans))
+		if language := data[i].Language; language != "" && !romanized(language) {
+			if _, ok := votes[language]; !ok {
+				order = append(order, language)
+			}
+			votes[language]++
+		}
+		if trans := data[i].TransLanguage; trans != "" && trans != data[i].Language {
+			votes[trans]--
+		}
+	}
+
+	preference := func(language string) int {
+		if index := slices.Index(preferred, language); index >= 0 {
+			return index
+		}
+		return len(preferred)
+	}
+	original := ""
+	for _, language := range order {
+		if votes[language] <= 0 {
+			continue
+		}
+		if original == "" || votes[language] > votes[original] ||
+			votes[language] == votes[original] && preference(language) < preference(original) {
+			original = language
+		}
+	}
+
+	// 原文语言的候选带的翻译的语言
+	translations := map[string]bool{}
+	for _, relation := range data {
+		if relation.Language == original && relation.TransLanguage != "" {
+			translations[relation.TransLanguage] = true
+		}
+	}
+	for i, relation := range data {
+		data[i].Translated = original != "" && relation.Language != "" && relation.Language != original && !romanized(relation.Language) &&
+			(translations[relation.Language] || relation.TransLanguage == original)
+	}
+
+	rank := func(relation model.MusicRelation) []int {
+		group := 1
+		switch {
+		case relation.Translated || romanized(relation.Language):
+			group = 2
+		case preference(relation.Language) < len(preferred):
+			group = 0
+		}
+		other := 1
+		if relation.Language == original {
+			other = 0
+		}
+		return []int{group, preference(relation.Language), other}
+	}
+	slices.SortStableFunc(data, func(a, b model.MusicRelation) int {
+		return slices.Compare(rank(a), rank(b))
+	})
+	return data
+}
+
+func romanized(language string) bool {
+	return strings.HasSuffix(language, "-Latn")
+}
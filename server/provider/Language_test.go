@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"encoding/base64"
+	"lyrics/model"
+	"slices"
+	"testing"
+)
+
+func languageRelation(lid string, lyrics string, trans string) model.MusicRelation {
+	encode := func(content string) string {
+		if content == "" {
+			return ""
+		}
+		return base64.StdEncoding.EncodeToString([]byte(content))
+	}
+	return model.MusicRelation{Lid: lid, Lyrics: encode(lyrics), Trans: encode(trans)}
+}
+
+func TestLanguages(t *testing.T) {
+	const (
+		ja     = "[00:01.00]君の名前を呼んだ\n[00:05.00]夜空に星が降る"
+		zh     = "[00:01.00]我呼唤了你的名字\n[00:05.00]星星从夜空落下"
+		romaji = "[00:01.00]kimi no namae wo yonda\n[00:05.00]yozora ni hoshi ga furu"
+		en     = "[00:01.00]I will always love you\n[00:05.00]and I can see the light"
+	)
+	lids := func(data []model.MusicRelation) []string {
+		var result []string
+		for _, relation := range data {
+			result = append(result, relation.Lid)
+		}
+		return result
+	}
+
+	// 把中文翻译作为歌词上传的候选被标记为翻译, 即使期望中文也排在原文后面
+	for _, preferred := range [][]string{nil, {"zh"}} {
+		data := Languages([]model.MusicRelation{
+			languageRelation("zh", zh, ""),
+			languageRelation("romaji", romaji, ""),
+			languageRelation("ja", ja, zh),
+			languageRelation("ja plain", ja, ""),
+		}, preferred)
+		if got := lids(data); !slices.Equal(got, []string{"ja", "ja plain", "zh", "romaji"}) {
+			t.Errorf("preferred %v: order = %v", preferred, got)
+		}
+		for _, relation := range data {
+			if want := relation.Lid == "zh"; relation.Translated != want {
+				t.Errorf("preferred %v: %s translated = %v, want %v", preferred, relation.Lid, relation.Translated, want)
+			}
+		}
+		if data[0].Language != "ja" || data[0].TransLanguage != "zh" || data[3].Language != "ja-Latn" {
+			t.Errorf("preferred %v: languages = %+v", preferred, data)
+		}
+	}
+
+	// 两种语言的原文票数相同时按期望的语言排序
+	for _, c := range []struct {
+		preferred []string
+		want      []string
+	}{
+		{nil, []string{"en", "ja"}},
+		{[]string{"ja"}, []string{"ja", "en"}},
+		{[]string{"ko", "en", "ja"}, []string{"en", "ja"}},
+	} {
+		data := Languages([]model.MusicRelation{languageRelation("en", en, ""), languageRelation("ja", ja, "")}, c.preferred)
+		if got := lids(data); !slices.Equal(got, c.want) {
+			t.Errorf("preferred %v: order = %v, want %v", c.preferred, got, c.want)
+		}
+		if data[0].Translated || data[1].Translated {
+			t.Errorf("preferred %v: originals marked as translated", c.preferred)
+		}
+	}
+}
@@ -16,12 +16,14 @@ import (
 
 type NetEaseLK struct{}
 
-func (search NetEaseLK) Lyrics(request model.SearchRequest) []model.MusicRelation {
+var netEaseLKClient = apputils.Client("NetEaseLK")
+
+func (search NetEaseLK) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
 	var result []model.MusicRelation
 
 	data, err := search.searchLK(request.Name + " " + request.Singer)
 	if err != nil {
-		return result
+		return result, err
 	}
 
 	if len(data.Result.Songs) < 1 {
@@ -39,22 +41,24 @@ func (search NetEaseLK) Lyrics(request model.SearchRequest) []model.MusicRelatio
 		}
 
 		lyrics, err := search.lyricsLK(song.ID)
-		if err != nil || lyrics == "" {
+		if err != nil || lyrics.Lyrics == "" {
 			continue
 		}
 
 		result = append(result, model.MusicRelation{
-			Name:   song.Name,
-			Singer: singer,
-			Lid:    strconv.Itoa(song.ID),
-			Sid:    request.Id,
-			Lyrics: base64.StdEncoding.EncodeToString([]byte(lyrics)),
-			Trans:  "",
-			Type:   NetEaseLKType, // Use the new constant
-			Offset: 0,
+			Name:    song.Name,
+			Singer:  singer,
+			Lid:     strconv.Itoa(song.ID),
+			Sid:     request.Id,
+			Lyrics:  base64.StdEncoding.EncodeToString([]byte(lyrics.Lyrics)),
+			Trans:   encodeOptional(lyrics.Trans),
+			Roma:    encodeOptional(lyrics.Roma),
+			Type:    NetEaseLKType, // Use the new constant
+			Offset:  0,
+			Credits: lyrics.Credits,
 		})
 	}
-	return result
+	return result, nil
 }
 
 func (search NetEaseLK) searchLK(keyword string) (model.NetEaseLKSearchResponse, error) {
@@ -75,22 +79,22 @@ func (search NetEaseLK) searchLK(keyword string) (model.NetEaseLKSearchResponse,
 	if err != nil {
 		return response, err
 	}
+	req = apputils.AllowRetry(req)
 	req.Header.Set("Referer", "http://music.163.com/")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15")
 
-	resp, err := apputils.C.Do(req)
+	resp, err := netEaseLKClient.Do(req)
 	if err != nil {
 		return response, err
 	}
 	
 	cookie := resp.Header.Get("Set-Cookie")
 	if cookie != "" {
+		_ = resp.Body.Close()
 		// Just send another GET with the cookie? Swift code does exactly that but with the same queryUrl?
 		// "The Swift implementation POSTs once, gets Set-Cookie, sets it, then re-GETs or re-POSTs"
 		// Actually, in Swift: `req.setValue(cookie, forHTTPHeaderField: "Cookie"); let (data, _) = try await URLSession.shared.data(for: req)`
 		req2, _ := http.NewRequest("POST", queryUrl, nil) // or GET? Swift was mutated req
 		req2.Header.Set("Referer", "http://music.163.com/")
-		req2.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15")
 		// parse cookie up to ;
 		for i := 0; i < len(cookie); i++ {
 			if cookie[i] == ';' {
@@ -99,30 +103,37 @@ func (search NetEaseLK) searchLK(keyword string) (model.NetEaseLKSearchResponse,
 			}
 		}
 		req2.Header.Set("Cookie", cookie)
-		resp, err = apputils.C.Do(req2)
+		req2 = apputils.AllowRetry(req2)
+		resp, err = netEaseLKClient.Do(req2)
 		if err != nil {
 			return response, err
 		}
 	}
 	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("NetEase (LK) search response %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
 	json.Unmarshal(body, &response)
 
 	return response, nil
 }
 
-func (search NetEaseLK) lyricsLK(id int) (string, error) {
+func (search NetEaseLK) lyricsLK(id int) (netEaseLyrics, error) {
 	lyricsURL := "https://interface3.music.163.com/eapi/song/lyric/v1"
 	data := map[string]interface{}{
 		"id":         strconv.Itoa(id),
 		"cp":         "false",
-		"lv":         "0",
-		"kv":         "0",
-		"tv":         "0",
-		"rv":         "0",
-		"yv":         "0",
-		"ytv":        "0",
-		"yrv":        "0",
+		"lv":         "-1",
+		"kv":         "-1",
+		"tv":         "-1",
+		"rv":         "-1",
+		"yv":         "1",
+		"ytv":        "1",
+		"yrv":        "1",
 		"csrf_token": "",
 	}
 
@@ -144,9 +155,11 @@ func (search NetEaseLK) lyricsLK(id int) (string, error) {
 
 	req, err := http.NewRequest("POST", reqURL, bytes.NewBufferString(formData.Encode()))
 	if err != nil {
-		return "", err
+		return netEaseLyrics{}, err
 	}
+	req = apputils.AllowRetry(req)
 
+	// eapi 需要和 buildEAPIHeader 一致的移动端 UA, 不使用配置中的 UA
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 9; PCT-AL10) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.64 HuaweiBrowser/10.0.3.311 Mobile Safari/537.36")
 	req.Header.Set("Referer", "https://music.163.com/")
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -158,26 +171,25 @@ func (search NetEaseLK) lyricsLK(id int) (string, error) {
 	}
 	req.Header.Set("Cookie", cookieStr)
 
-	resp, err := apputils.C.Do(req)
+	resp, err := netEaseLKClient.Do(req)
 	if err != nil {
-		return "", err
+		return netEaseLyrics{}, err
 	}
 	defer resp.Body.Close()
-	
-	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return netEaseLyrics{}, fmt.Errorf("NetEase (LK) lyric response %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return netEaseLyrics{}, err
+	}
 	var singleLyricsResponse model.NetEaseLKSingleLyricsResponse
 	json.Unmarshal(body, &singleLyricsResponse)
 
-	lyr := ""
-	if singleLyricsResponse.Yrc.Lyric != "" {
-		lyr = singleLyricsResponse.Yrc.Lyric
-	} else if singleLyricsResponse.KLyric.Lyric != "" {
-		lyr = singleLyricsResponse.KLyric.Lyric
-	} else if singleLyricsResponse.Lrc.Lyric != "" {
-		lyr = singleLyricsResponse.Lrc.Lyric
-	} else {
-		return "", fmt.Errorf("no lyric found")
+	lyrics := netEaseLayers(singleLyricsResponse, true)
+	if lyrics.Lyrics == "" {
+		return lyrics, fmt.Errorf("no lyric found")
 	}
-
-	return lyr, nil
+	return lyrics, nil
 }
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"lyrics/lyric"
+	"lyrics/model"
+	"strings"
+)
+
+// 翻译 / 罗马音与原文时间戳允许的误差 (ms)
+const netEaseAlignTolerance = 500
+
+type netEaseLyrics struct {
+	Lyrics  string
+	Trans   string
+	Roma    string
+	Credits *model.LyricCredits
+}
+
+// netEaseLayers 选出原文, 并把翻译 / 罗马音对齐到原文的时间戳; wordTiming 时优先逐字的 YRC
+func netEaseLayers(response model.NetEaseLKSingleLyricsResponse, wordTiming bool) netEaseLyrics {
+	var result netEaseLyrics
+	trans := response.TLyric.Lyric
+	roma := response.RomaLrc.Lyric
+
+	switch {
+	case wordTiming && response.Yrc.Lyric != "":
+		result.Lyrics = response.Yrc.Lyric
+		// YRC 有自己的一套翻译 / 罗马音, 时间戳和 YRC 的行一致
+		trans = firstNonBlank(response.YtLrc.Lyric, trans)
+		roma = firstNonBlank(response.YRomaLrc.Lyric, roma)
+	case wordTiming && response.KLyric.Lyric != "":
+		result.Lyrics = response.KLyric.Lyric
+	default:
+		result.Lyrics = response.Lrc.Lyric
+	}
+	if strings.TrimSpace(result.Lyrics) == "" {
+		return result
+	}
+
+	main := lyric.Parse(result.Lyrics)
+	result.Trans = alignLayer(main, trans)
+	result.Roma = alignLayer(main, roma)
+
+	if response.LyricUser.Nickname != "" || response.TransUser.Nickname != "" {
+		result.Credits = &model.LyricCredits{
+			Uploader:   response.LyricUser.Nickname,
+			Translator: response.TransUser.Nickname,
+		}
+	}
+	return result
+}
+
+func alignLayer(main lyric.Lyric, layer string) string {
+	if strings.TrimSpace(layer) == "" {
+		return ""
+	}
+	aligned := lyric.Align(main, lyric.Parse(layer), netEaseAlignTolerance)
+	if len(aligned.Lines) == 0 {
+		return ""
+	}
+	return lyric.Format(aligned)
+}
+
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -13,23 +13,25 @@ import (
 	"strings"
 )
 
+var netEaseClient = apputils.Client("NetEaseMusic")
+
 var netEaseMusicSearch = "http://music.163.com/api/search/pc"
-var netEaseLyrics = "https://music.163.com/api/song/media?id=%d"
+var netEaseLyricsUrl = "https://music.163.com/api/song/lyric?id=%d&lv=-1&kv=-1&tv=-1&rv=-1"
 
 type NetEaseMusic struct{}
 
-func (search NetEaseMusic) Lyrics(request model.SearchRequest) []model.MusicRelation {
+func (search NetEaseMusic) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
 	var result []model.MusicRelation
 
-	data, done := search.search(request.Name + " " + request.Singer)
-	if done {
-		return result
+	data, err := search.search(request.Name + " " + request.Singer)
+	if err != nil {
+		return result, err
 	}
 
 	if len(data.Result.Songs) < 1 {
-		data, done = search.search(request.Name)
-		if done {
-			return result
+		data, err = search.search(request.Name)
+		if err != nil {
+			return result, err
 		}
 		if len(data.Result.Songs) < 1 {
 			// log.Printf("Not Get song info [%s]", )
@@ -41,9 +43,12 @@ func (search NetEaseMusic) Lyrics(request model.SearchRequest) []model.MusicRela
 				}
 			}
 			if minIndex == len(request.Name) {
-				return result
+				return result, nil
+			}
+			data, err = search.search(request.Name[:minIndex])
+			if err != nil {
+				return result, err
 			}
-			data, done = search.search(request.Name[:minIndex])
 		}
 	}
 	for _, song := range data.Result.Songs {
@@ -58,7 +63,7 @@ func (search NetEaseMusic) Lyrics(request model.SearchRequest) []model.MusicRela
 			log.Printf(err.Error())
 			continue
 		}
-		if lyrics == "" {
+		if lyrics.Lyrics == "" {
 			continue
 		}
 		encoding := base64.StdEncoding
@@ -68,16 +73,18 @@ func (search NetEaseMusic) Lyrics(request model.SearchRequest) []model.MusicRela
 			Lid:    strconv.Itoa(song.Id),
 			Sid:    request.Id,
 			// 获取歌词
-			Lyrics: encoding.EncodeToString([]byte(lyrics)),
-			Trans:  "",
-			Type:   NetEase,
-			Offset: 0,
+			Lyrics:  encoding.EncodeToString([]byte(lyrics.Lyrics)),
+			Trans:   encodeOptional(lyrics.Trans),
+			Roma:    encodeOptional(lyrics.Roma),
+			Type:    NetEase,
+			Offset:  0,
+			Credits: lyrics.Credits,
 		})
 	}
-	return result
+	return result, nil
 }
 
-func (search NetEaseMusic) search(key string) (model.NetEaseSearchResponse, bool) {
+func (search NetEaseMusic) search(key string) (model.NetEaseSearchResponse, error) {
 	key, err := apputils.T2s(key)
 	params := url.Values{}
 	params.Add("offset", "0")
@@ -101,20 +108,22 @@ func (search NetEaseMusic) search(key string) (model.NetEaseSearchResponse, bool
 		"Referer":                   "http://music.163.com/",
 		// ":scheme":                   "https",
 		// ":authority": "music.163.com",
-		"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15",
 		// "Cookie":     cookie[:strings.Index(cookie, ";")],
 	}
 
 	var response model.NetEaseSearchResponse
 	req, err := http.NewRequest("GET", queryUrl, nil)
+	if err != nil {
+		return response, err
+	}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	resp, err := apputils.C.Do(req)
+	resp, err := netEaseClient.Do(req)
 	if err != nil {
-		log.Printf("[ERROR] GET Cookie Failed NetEase Music Search Error: %s", err.Error())
-		return response, true
+		return response, fmt.Errorf("GET Cookie Failed NetEase Music Search Error: %w", err)
 	}
+	_ = resp.Body.Close()
 	// resp, err := http.Get(url)
 	// if err != nil {
 	// 	log.Printf("[ERROR] GET Cookie Failed NetEase Music Search Error: %s", err.Error())
@@ -122,30 +131,30 @@ func (search NetEaseMusic) search(key string) (model.NetEaseSearchResponse, bool
 	// }
 	cookie := resp.Header.Get("Set-Cookie")
 	if len(cookie) < 1 {
-		log.Printf("[ERROR] GET Cookie Failed NetEase Music Search Error")
-		return response, true
+		return response, errors.New("GET Cookie Failed NetEase Music Search Error")
+	}
+	if i := strings.Index(cookie, ";"); i >= 0 {
+		cookie = cookie[:i]
 	}
-	headers["Cookie"] = cookie[:strings.Index(cookie, ";")]
-	response, err = apputils.HttpGet[model.NetEaseSearchResponse](queryUrl, headers)
+	headers["Cookie"] = cookie
+	response, err = apputils.HttpGet[model.NetEaseSearchResponse](netEaseClient, queryUrl, headers)
 	if err != nil {
-		log.Printf("[ERROR] Failed Get NetEase Music [%s - %s]: %s", key, queryUrl, err)
-		return response, true
+		return response, fmt.Errorf("Failed Get NetEase Music [%s - %s]: %w", key, queryUrl, err)
 	}
 	if response.Code != 200 {
-		log.Printf("[ERROR] Failed Get NetEase Music [%s - %s]: %d", key, queryUrl, response.Code)
-		return response, true
+		return response, fmt.Errorf("Failed Get NetEase Music [%s - %s]: %d", key, queryUrl, response.Code)
 	}
 
-	return response, false
+	return response, nil
 }
 
-func (search NetEaseMusic) lyrics(id int) (string, error) {
-	response, err := apputils.HttpGet[model.NetEaseLyricsResponse](fmt.Sprintf(netEaseLyrics, id), map[string]string{})
+func (search NetEaseMusic) lyrics(id int) (netEaseLyrics, error) {
+	response, err := apputils.HttpGet[model.NetEaseLKSingleLyricsResponse](netEaseClient, fmt.Sprintf(netEaseLyricsUrl, id), map[string]string{})
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("[ERROR] Failed Get NetEase Lyrics [%d - %s]: %s", id, lyricsBaseUrl, err))
+		return netEaseLyrics{}, errors.New(fmt.Sprintf("[ERROR] Failed Get NetEase Lyrics [%d - %s]: %s", id, netEaseLyricsUrl, err))
 	}
 	if response.Code != 200 {
-		return "", errors.New(fmt.Sprintf("[ERROR] Failed to get NetEase lyrics for Music [%d - %s]", id, strconv.Itoa(response.Code)))
+		return netEaseLyrics{}, errors.New(fmt.Sprintf("[ERROR] Failed to get NetEase lyrics for Music [%d - %s]", id, strconv.Itoa(response.Code)))
 	}
-	return response.Lyric, nil
+	return netEaseLayers(response, false), nil
 }
@@ -0,0 +1,332 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"lyrics/config"
+	"lyrics/model"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pluginOneshot = "oneshot"
+	pluginJsonl   = "jsonl"
+)
+
+var errOutputLimit = errors.New("plugin output exceeds max_output")
+
+// Plugin 外部进程歌词源, jsonl 模式下进程常驻, 请求串行处理
+type Plugin struct {
+	Definition config.PluginProvider
+
+	mu       sync.Mutex
+	process  *pluginProcess
+	failures int
+	retryAt  time.Time
+	// jsonl 模式下最后一个请求的 id
+	sequence uint64
+}
+
+// pluginRequest jsonl 模式的一行请求, 响应需要带上同样的 request_id
+type pluginRequest struct {
+	model.SearchRequest
+	RequestId uint64 `json:"request_id"`
+}
+
+// pluginReply jsonl 模式的一行响应
+type pluginReply struct {
+	RequestId uint64          `json:"request_id"`
+	Results   json.RawMessage `json:"results"`
+}
+
+type pluginProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan pluginLine
+	// 进程被放弃后关闭, 避免读取协程阻塞
+	done chan struct{}
+}
+
+type pluginLine struct {
+	data []byte
+	err  error
+}
+
+// Plugins 校验配置中的插件, 不完整的定义会被跳过
+func Plugins(definitions []config.PluginProvider) []Provider {
+	var result []Provider
+	for _, definition := range definitions {
+		if definition.Mode == "" {
+			definition.Mode = pluginOneshot
+		}
+		if definition.Timeout <= 0 {
+			definition.Timeout = 10 * time.Second
+		}
+		if definition.MaxOutput <= 0 {
+			definition.MaxOutput = 4 << 20
+		}
+		if err := validatePlugin(definition); err != nil {
+			log.Printf("[ERROR] Skip Plugin Provider [%s]: %s", definition.Name, err)
+			continue
+		}
+		log.Printf("[INFO] Plugin Provider Registered [%s] (%s)", definition.Name, definition.Mode)
+		result = append(result, &Plugin{Definition: definition})
+	}
+	return result
+}
+
+func validatePlugin(definition config.PluginProvider) error {
+	if definition.Name == "" {
+		return errors.New("name is required")
+	}
+	if reservedName(definition.Name) {
+		return fmt.Errorf("name %q is used by a built-in provider", definition.Name)
+	}
+	if definition.Command == "" {
+		return errors.New("command is required")
+	}
+	if definition.Mode != pluginOneshot && definition.Mode != pluginJsonl {
+		return fmt.Errorf("unknown mode %q", definition.Mode)
+	}
+	return nil
+}
+
+func (p *Plugin) Name() string {
+	return p.Definition.Name
+}
+
+func (p *Plugin) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
+	var result []model.MusicRelation
+
+	var output []byte
+	var err error
+	if p.Definition.Mode == pluginJsonl {
+		output, err = p.call(request)
+	} else {
+		output, err = p.oneshot(request)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for i := range result {
+		result[i].Sid = request.Id
+		if result[i].Type == "" {
+			result[i].Type = p.Definition.Name
+		}
+	}
+	return result, nil
+}
+
+// oneshot 每次请求启动一个进程, 超时后进程会被杀掉
+func (p *Plugin) oneshot(request model.SearchRequest) ([]byte, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.Definition.Timeout)
+	defer cancel()
+
+	cmd := p.command(ctx)
+	stdout := &limitedBuffer{max: p.Definition.MaxOutput}
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = stdout
+	cmd.Stderr = pluginStderr{name: p.Definition.Name}
+
+	err = cmd.Run()
+	if stdout.overflow {
+		return nil, errOutputLimit
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timeout after %s", p.Definition.Timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// call 向常驻进程写入一行带 request_id 的请求, 等待 request_id 相同的一行响应, 其他响应 (之前超时的请求等) 丢弃;
+// 出错或超时时杀掉进程, 下次请求按退避时间重启
+func (p *Plugin) call(request model.SearchRequest) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.process == nil {
+		if wait := time.Until(p.retryAt); wait > 0 {
+			return nil, fmt.Errorf("restarting in %s", wait.Round(time.Millisecond))
+		}
+		process, err := p.start()
+		if err != nil {
+			p.fail()
+			return nil, err
+		}
+		p.process = process
+	}
+
+	p.sequence++
+	payload, err := json.Marshal(pluginRequest{SearchRequest: request, RequestId: p.sequence})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.process.stdin.Write(append(payload, '\n')); err != nil {
+		p.fail()
+		return nil, err
+	}
+
+	timer := time.NewTimer(p.Definition.Timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-p.process.lines:
+			if !ok {
+				p.fail()
+				return nil, errors.New("plugin exited")
+			}
+			if line.err != nil {
+				p.fail()
+				return nil, line.err
+			}
+			var reply pluginReply
+			if err := json.Unmarshal(line.data, &reply); err != nil || reply.RequestId != p.sequence {
+				log.Printf("[ERROR] Plugin %s: discard response without request_id %d", p.Definition.Name, p.sequence)
+				continue
+			}
+			p.failures = 0
+			return reply.Results, nil
+		case <-timer.C:
+			p.fail()
+			return nil, fmt.Errorf("timeout after %s", p.Definition.Timeout)
+		}
+	}
+}
+
+func (p *Plugin) start() (*pluginProcess, error) {
+	cmd := p.command(context.Background())
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = pluginStderr{name: p.Definition.Name}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Plugin %s Started (pid %d)", p.Definition.Name, cmd.Process.Pid)
+
+	process := &pluginProcess{cmd: cmd, stdin: stdin, lines: make(chan pluginLine, 1), done: make(chan struct{})}
+	go func() {
+		defer close(process.lines)
+		reader := bufio.NewReader(stdout)
+		for {
+			data, err := readLine(reader, p.Definition.MaxOutput)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					process.send(pluginLine{err: err})
+				}
+				_ = cmd.Process.Kill()
+				_ = cmd.Wait()
+				return
+			}
+			if len(data) > 0 {
+				process.send(pluginLine{data: data})
+			}
+		}
+	}()
+	return process, nil
+}
+
+func (process *pluginProcess) send(line pluginLine) {
+	select {
+	case process.lines <- line:
+	case <-process.done:
+	}
+}
+
+// fail 杀掉当前进程并计算下次重启时间, 连续失败时从 1s 开始翻倍, 最多 1 分钟
+func (p *Plugin) fail() {
+	if p.process != nil {
+		_ = p.process.stdin.Close()
+		_ = p.process.cmd.Process.Kill()
+		close(p.process.done)
+		p.process = nil
+	}
+	backoff := time.Second << min(p.failures, 6)
+	if backoff > time.Minute {
+		backoff = time.Minute
+	}
+	p.failures++
+	p.retryAt = time.Now().Add(backoff)
+}
+
+func (p *Plugin) command(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.Definition.Command, p.Definition.Args...)
+	cmd.Dir = p.Definition.Dir
+	// 超时被杀掉后, 不再等待子进程继续持有的输出管道
+	cmd.WaitDelay = time.Second
+	cmd.Env = os.Environ()
+	for k, v := range p.Definition.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	return cmd
+}
+
+// readLine 读取一行, 超过 max 字节时返回 errOutputLimit
+func readLine(reader *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > max {
+			return nil, errOutputLimit
+		}
+		if err == nil {
+			return bytes.TrimSpace(line), nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, err
+		}
+	}
+}
+
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if b.buf.Len()+len(data) > b.max {
+		b.overflow = true
+		return 0, errOutputLimit
+	}
+	return b.buf.Write(data)
+}
+
+// pluginStderr 把插件的 stderr 写入日志
+type pluginStderr struct {
+	name string
+}
+
+func (w pluginStderr) Write(data []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		log.Printf("[INFO] Plugin %s: %s", w.name, line)
+	}
+	return len(data), nil
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"lyrics/config"
+	"lyrics/model"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 第一个请求的响应前后多输出几行 (其他 request_id / 没有 request_id / 重复的响应), 第二个请求不能收到这些行
+func TestPluginMatchesRequestId(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "plugin.sh")
+	body := `#!/bin/sh
+while read -r line; do
+  id=$(echo "$line" | sed 's/.*"request_id":\([0-9]*\).*/\1/')
+  if [ "$id" = 1 ]; then
+    echo '{"request_id":99,"results":[{"lid":"stale"}]}'
+    echo '[{"lid":"bare"}]'
+  fi
+  echo "{\"request_id\":$id,\"results\":[{\"type\":\"p\",\"lid\":\"$id\",\"lyrics\":\"[00:01.00]a\"}]}"
+  if [ "$id" = 1 ]; then
+    echo '{"request_id":1,"results":[{"lid":"extra"}]}'
+  fi
+done
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plugins := Plugins([]config.PluginProvider{{Name: "p", Command: script, Mode: pluginJsonl, Timeout: 5 * time.Second}})
+	plugin := plugins[0].(*Plugin)
+	defer plugin.fail()
+
+	for i, want := range []string{"1", "2"} {
+		result, err := plugin.Lyrics(model.SearchRequest{Name: "t"})
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if len(result) != 1 || result[0].Lid != want {
+			t.Fatalf("request %d: got %+v, want lid %s", i, result, want)
+		}
+	}
+}
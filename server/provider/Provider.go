@@ -1,6 +1,9 @@
 package provider
 
-import "lyrics/model"
+import (
+	"lyrics/model"
+	"strings"
+)
 
 const (
 	QQ        = "QQ Music"
@@ -12,7 +15,23 @@ const (
 	QQMusicLKType = "QQ Music (LK)"
 )
 
+// builtinNames 内置 Provider 的歌词类型 (Type) 和出站客户端的名字, 配置中的 Provider 不能使用
+var builtinNames = []string{
+	QQ, KuGou, NetEase, LRCLIBType, KugouLKType, NetEaseLKType, QQMusicLKType,
+	"QQMusicLyrics", "QQMusicLK", "KugouMusic", "KugouLK", "NetEaseMusic", "NetEaseLK",
+}
+
+// reservedName 名字与内置 Provider 相同 (不区分大小写)
+func reservedName(name string) bool {
+	for _, builtin := range builtinNames {
+		if strings.EqualFold(strings.TrimSpace(name), builtin) {
+			return true
+		}
+	}
+	return false
+}
+
 type Provider interface {
-	// Lyrics Base64 字符串
-	Lyrics(request model.SearchRequest) []model.MusicRelation
+	// Lyrics Base64 字符串, error 表示上游不可用 (搜索失败), 单首歌词获取失败只记日志
+	Lyrics(request model.SearchRequest) ([]model.MusicRelation, error)
 }
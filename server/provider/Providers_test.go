@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"encoding/base64"
+	apputils "lyrics/app-utils"
+	"lyrics/lyric"
+	"lyrics/model"
+	"lyrics/replay/replaytest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixture 是按各平台接口格式手写的合成数据, 不是 LYRICS_HTTP_RECORD 录制的真实响应, 见 README
+var fixtureRequest = model.SearchRequest{
+	Id:       "4iV5W9uYEdYUVa79Axb7Rh",
+	Name:     "晴天",
+	Singer:   "周杰伦",
+	Album:    "叶惠美",
+	Duration: 269000,
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// Persist 在包初始化时会创建数据库文件
+	_ = os.Remove(path)
+	os.Exit(code)
+}
+
+// replayFixture 把 Provider 的客户端指向 testdata/<name>.json 的回放服务器
+func replayFixture(t *testing.T, name string) {
+	t.Helper()
+	server := replaytest.NewServer(t, filepath.Join("testdata", name+".json"))
+	transport := apputils.Client(name).Transport.(*apputils.Transport)
+	base := transport.Base
+	transport.Base = server.Transport()
+	t.Cleanup(func() { transport.Base = base })
+}
+
+func search(t *testing.T, p Provider) []model.MusicRelation {
+	t.Helper()
+	replayFixture(t, providerName(p))
+	result, err := p.Lyrics(fixtureRequest)
+	if err != nil {
+		t.Fatalf("Lyrics: %s", err)
+	}
+	if len(result) == 0 {
+		t.Fatal("no result")
+	}
+	return result
+}
+
+func decodeLayer(t *testing.T, content string) lyric.Lyric {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		t.Fatalf("not base64: %s", err)
+	}
+	return lyric.Parse(string(raw))
+}
+
+// checkRelation 校验公共字段和原文的前两行
+func checkRelation(t *testing.T, relation model.MusicRelation, kind string, lid string, words bool) lyric.Lyric {
+	t.Helper()
+	if relation.Type != kind {
+		t.Errorf("type = %q, want %q", relation.Type, kind)
+	}
+	if relation.Lid != lid {
+		t.Errorf("lid = %q, want %q", relation.Lid, lid)
+	}
+	if relation.Sid != fixtureRequest.Id {
+		t.Errorf("sid = %q, want %q", relation.Sid, fixtureRequest.Id)
+	}
+
+	lyrics := decodeLayer(t, relation.Lyrics)
+	if len(lyrics.Lines) < 2 {
+		t.Fatalf("lines = %d, want 2", len(lyrics.Lines))
+	}
+	first := lyrics.Lines[0]
+	if first.Start != 1000 || first.Text != "故事的小黄花" {
+		t.Errorf("first line = %d %q", first.Start, first.Text)
+	}
+	if lyrics.Lines[1].Start != 5500 || lyrics.Lines[1].Text != "从出生那年就飘着" {
+		t.Errorf("second line = %d %q", lyrics.Lines[1].Start, lyrics.Lines[1].Text)
+	}
+	if words && len(first.Words) != 6 {
+		t.Errorf("words = %d, want 6", len(first.Words))
+	}
+	return lyrics
+}
+
+func checkLayer(t *testing.T, name string, content string, want string) {
+	t.Helper()
+	layer := decodeLayer(t, content)
+	if len(layer.Lines) == 0 || layer.Lines[0].Text != want {
+		t.Errorf("%s = %+v, want first line %q", name, layer.Lines, want)
+	}
+}
+
+func TestQQMusicLyrics(t *testing.T) {
+	result := search(t, QQMusicLyrics{})
+	// 歌名和歌名+歌手两次搜索命中同一首歌
+	if len(result) != 2 {
+		t.Errorf("results = %d, want 2", len(result))
+	}
+	checkRelation(t, result[0], QQ, "0039MnYb0qxYhV", false)
+	checkLayer(t, "trans", result[0].Trans, "The little yellow flower of the story")
+}
+
+func TestQQMusicLK(t *testing.T) {
+	result := search(t, QQMusicLK{})
+	checkRelation(t, result[0], QQMusicLKType, "0039MnYb0qxYhV", true)
+	checkLayer(t, "trans", result[0].Trans, "The little yellow flower of the story")
+	checkLayer(t, "roma", result[0].Roma, "gu shi de xiao huang hua")
+}
+
+func TestNetEaseMusic(t *testing.T) {
+	result := search(t, NetEaseMusic{})
+	checkRelation(t, result[0], NetEase, "186016", false)
+	checkLayer(t, "trans", result[0].Trans, "The little yellow flower of the story")
+	checkLayer(t, "roma", result[0].Roma, "gu shi de xiao huang hua")
+	if result[0].Singer != "周杰伦" {
+		t.Errorf("singer = %q", result[0].Singer)
+	}
+}
+
+func TestNetEaseLK(t *testing.T) {
+	result := search(t, NetEaseLK{})
+	checkRelation(t, result[0], NetEaseLKType, "186016", true)
+	checkLayer(t, "trans", result[0].Trans, "The little yellow flower of the story")
+	credits := result[0].Credits
+	if credits == nil || credits.Uploader != "uploader" || credits.Translator != "translator" {
+		t.Errorf("credits = %+v", credits)
+	}
+}
+
+func TestKugouMusic(t *testing.T) {
+	result := search(t, KugouMusic{})
+	checkRelation(t, result[0], KuGou, "8B0D1F8E2A0E1B7A9A0F0C8F6D1E2C3B-21914744-D1B2C3A4E5F60718293A4B5C6D7E8F90", true)
+}
+
+func TestKugouLK(t *testing.T) {
+	result := search(t, KugouLK{})
+	checkRelation(t, result[0], KugouLKType, "8B0D1F8E2A0E1B7A9A0F0C8F6D1E2C3B", true)
+}
+
+func TestLRCLIB(t *testing.T) {
+	result := search(t, LRCLIB{})
+	if len(result) != 1 {
+		t.Errorf("results = %d, want exact match only", len(result))
+	}
+	checkRelation(t, result[0], LRCLIBType, "1", false)
+}
+
+func TestMissingFixture(t *testing.T) {
+	replayFixture(t, "LRCLIB")
+	request := fixtureRequest
+	request.Duration = 0
+	if _, err := (LRCLIB{}).Lyrics(request); err == nil {
+		t.Error("expected error for request without fixture")
+	}
+}
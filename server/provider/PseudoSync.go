@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"encoding/base64"
+	"lyrics/lyric"
+	"lyrics/model"
+	"strings"
+)
+
+// 参考时间轴与纯文本歌词相同行的比例至少为这个值才认为是同一首歌 (逐行歌词中插入的翻译会拉低比例)
+const referenceSimilarity = 0.5
+
+// PseudoSync 为只有纯文本歌词的候选估计时间轴, 标记为 Estimated.
+// 参考时间轴取文字与它最相似 (按 Dedup 的指纹) 的逐行候选, 都不够相似时只按歌曲时长估计
+func PseudoSync(data []model.MusicRelation, duration int64) []model.MusicRelation {
+	var timed []candidate
+	var references []lyric.Lyric
+	for _, relation := range data {
+		if timeline(relation) == nil {
+			continue
+		}
+		timed = append(timed, newCandidate(relation))
+		references = append(references, lyric.Parse(lyric.Clean(lyric.Decode(relation.Lyrics), fingerprintOptions, nil)))
+	}
+
+	for i, relation := range data {
+		content := lyric.Decode(relation.Lyrics)
+		if !lyric.Plain(content) {
+			continue
+		}
+		plain := plainCandidate(content)
+		var reference lyric.Lyric
+		best := 0.0
+		for j, c := range timed {
+			if s := similarity(plain, c); s >= referenceSimilarity && s > best {
+				reference, best = references[j], s
+			}
+		}
+		if synced, ok := lyric.PseudoSync(content, duration, reference); ok {
+			data[i].Lyrics = base64.StdEncoding.EncodeToString([]byte(lyric.Format(synced)))
+			data[i].Estimated = true
+		}
+	}
+	return data
+}
+
+// plainCandidate 纯文本歌词按行计算指纹
+func plainCandidate(content string) candidate {
+	c := candidate{fingerprint: map[string]int{}}
+	for _, line := range strings.Split(lyric.Clean(content, fingerprintOptions, nil), "\n") {
+		if text := normalizeLine(line); text != "" {
+			c.fingerprint[text]++
+			c.lines++
+		}
+	}
+	return c
+}
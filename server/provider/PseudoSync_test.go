@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"encoding/base64"
+	"lyrics/lyric"
+	"lyrics/model"
+	"strings"
+	"testing"
+)
+
+// 纯文本歌词以文字最相似的逐行候选为参考, 不使用排在前面的另一首歌
+func TestPseudoSyncReference(t *testing.T) {
+	other := syntheticRelation("other", []string{"窗外的麻雀", "在电线杆上多嘴", "你说这一句", "很有夏天的感觉", "手中的铅笔", "在纸上来来回回"}, 0)
+	same := syntheticRelation("same", syntheticTexts, 500)
+	plain := model.MusicRelation{Lid: "plain", Lyrics: base64.StdEncoding.EncodeToString([]byte(strings.Join(syntheticTexts, "\n")))}
+	result := PseudoSync([]model.MusicRelation{other, same, plain}, 269000)
+	if !result[2].Estimated {
+		t.Fatal("plain lyrics not estimated")
+	}
+	lines := lyric.Parse(lyric.Decode(result[2].Lyrics)).Lines
+	if len(lines) != len(syntheticTexts) || lines[0].Start != 1500 || lines[5].Start != 21500 {
+		t.Errorf("lines = %+v, want the timeline of %q", lines, same.Lid)
+	}
+
+	// 没有相似的候选时按时长估计
+	result = PseudoSync([]model.MusicRelation{other, plain}, 269000)
+	if lines := lyric.Parse(lyric.Decode(result[1].Lyrics)).Lines; len(lines) == 0 || lines[0].Start != 20000 {
+		t.Errorf("lines = %+v, want estimated from duration", lines)
+	}
+}
@@ -3,10 +3,13 @@ package provider
 import (
 	"encoding/base64"
 	"fmt"
+	"html"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	apputils "lyrics/app-utils"
@@ -15,112 +18,190 @@ import (
 
 type QQMusicLK struct{}
 
-func (search QQMusicLK) searchLK(keyword string) ([]model.QQMusicLKSearchResponse, error) {
+var qqMusicLKClient = apputils.Client("QQMusicLK")
+
+var qqLKSearch = "https://c.y.qq.com/splcloud/fcgi-bin/smartbox_new.fcg?key=%s"
+var qqLKSongDetail = "https://c.y.qq.com/v8/fcg-bin/fcg_play_single_song.fcg?songmid=%s&tpl=yqq_song_detail&format=json"
+var qqLKDownload = "https://c.y.qq.com/qqmusic/fcgi-bin/lyric_download.fcg?musicid=%d&version=15&miniversion=82&lrctype=4"
+
+var (
+	qrcLyricsTag = regexp.MustCompile(`(?s)<content>(.*?)</content>`)
+	qrcTransTag  = regexp.MustCompile(`(?s)<contentts>(.*?)</contentts>`)
+	qrcRomaTag   = regexp.MustCompile(`(?s)<contentroma>(.*?)</contentroma>`)
+	qrcXmlBody   = regexp.MustCompile(`(?s)LyricContent="([^"]*)"`)
+)
+
+// qqLKLyrics 解密后的 QRC 原文 / 翻译 / 罗马音
+type qqLKLyrics struct {
+	Lyrics string
+	Trans  string
+	Roma   string
+}
+
+func (search QQMusicLK) searchLK(keyword string) (model.QQMusicLKSearchResponse, error) {
 	key, err := apputils.T2s(keyword)
 	if err != nil {
 		key = keyword
 	}
 
-	searchURL := "https://c.y.qq.com/splcloud/fcgi-bin/smartbox_new.fcg?key=" + url.QueryEscape(key)
-	
-	resp, err := apputils.HttpGet[model.QQMusicLKSearchResponse](searchURL, nil)
+	resp, err := apputils.HttpGet[model.QQMusicLKSearchResponse](qqMusicLKClient, fmt.Sprintf(qqLKSearch, url.QueryEscape(key)), nil)
 	if err != nil {
-		return nil, err
+		return resp, err
 	}
-	
 	if resp.Code != 0 {
-		return nil, fmt.Errorf("QQMusic LK search error: %d", resp.Code)
+		return resp, fmt.Errorf("QQMusic LK search error: %d", resp.Code)
 	}
+	return resp, nil
+}
 
-	return []model.QQMusicLKSearchResponse{resp}, nil
+// songId smartbox 有时不返回数字 ID, 这时通过歌曲详情用 mid 查询
+func (search QQMusicLK) songId(id string, mid string) (int64, error) {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && n > 0 {
+		return n, nil
+	}
+	headers := map[string]string{"Referer": "https://y.qq.com/"}
+	detail, err := apputils.HttpGet[model.QQMusicSongDetailResponse](qqMusicLKClient, fmt.Sprintf(qqLKSongDetail, mid), headers)
+	if err != nil {
+		return 0, err
+	}
+	if detail.Code != 0 || len(detail.Data) < 1 {
+		return 0, fmt.Errorf("QQMusic LK song detail error [%s]: %d", mid, detail.Code)
+	}
+	return detail.Data[0].ID, nil
 }
 
-func (search QQMusicLK) lyricsLK(mid string) (string, error) {
-	// Let's use QQ LK endpoint 2: https://c.y.qq.com/qqmusic/fcgi-bin/lyric_download.fcg
-	// The swift parameter uses musicid = id. But in smartbox return, we have mid. Do we have id?
-	// The item struct in Swift has `mid`, `name`, `singer`, `id`.
-	// Wait, the LK response searches smartbox, mid is mid. Id is id. smartbox returns both id and mid.
-	// Oh! Currently the search result only returns `mid`. We added `id` to the struct `QQMusicLKSearchResponse`.
-	
-	idStr := ""
-	if idStr == "" {
-		// Just use endpoint 1: https://c.y.qq.com/lyric/fcgi-bin/fcg_query_lyric_new.fcg
-		// lyricsBaseUrl1 : https://c.y.qq.com/lyric/fcgi-bin/fcg_query_lyric_new.fcg?songmid=%s&g_tk=5381&format=json
-		return "", fmt.Errorf("id is required for qrc endpoint")
-	}
-	return "", nil
+// lyricsLK 通过 lyric_download.fcg 获取逐字 QRC, 同时解密翻译和罗马音
+func (search QQMusicLK) lyricsLK(id int64) (qqLKLyrics, error) {
+	var result qqLKLyrics
+
+	req, err := http.NewRequest("GET", fmt.Sprintf(qqLKDownload, id), nil)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Referer", "y.qq.com/portal/player.html")
+
+	// 这个接口返回的是 XML, 不能用 apputils.HttpGet
+	res, err := qqMusicLKClient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	bodyBytes, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return result, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("QQ Music lyric_download response %d", res.StatusCode)
+	}
+	body := strings.ReplaceAll(string(bodyBytes), "<!--", "")
+	body = strings.ReplaceAll(body, "-->", "")
+
+	result.Lyrics, err = search.decryptTag(qrcLyricsTag, body)
+	if err != nil {
+		return result, err
+	}
+	if result.Lyrics == "" {
+		return result, fmt.Errorf("no qrc content for %d", id)
+	}
+	// 翻译和罗马音解密失败不影响原文
+	if result.Trans, err = search.decryptTag(qrcTransTag, body); err != nil {
+		log.Printf("[ERROR] Failed Decrypt QQ Music Trans [%d]: %s", id, err)
+	}
+	if result.Roma, err = search.decryptTag(qrcRomaTag, body); err != nil {
+		log.Printf("[ERROR] Failed Decrypt QQ Music Roma [%d]: %s", id, err)
+	}
+	return result, nil
 }
 
-func (search QQMusicLK) Lyrics(request model.SearchRequest) []model.MusicRelation {
+func (search QQMusicLK) decryptTag(tag *regexp.Regexp, body string) (string, error) {
+	matches := tag.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		return "", nil
+	}
+	content := strings.TrimSpace(matches[1])
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "<![CDATA["), "]]>")
+	if content == "" {
+		return "", nil
+	}
+	decrypted, err := decryptQQMusicQrc(content)
+	if err != nil {
+		return "", err
+	}
+	return unwrapQrc(decrypted), nil
+}
+
+// unwrapQrc 解密后的 QRC 包在 XML 的 LyricContent 属性里, 只保留歌词本身
+func unwrapQrc(content string) string {
+	if m := qrcXmlBody.FindStringSubmatch(content); len(m) > 1 {
+		return html.UnescapeString(m[1])
+	}
+	return content
+}
+
+func (search QQMusicLK) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
 	var result []model.MusicRelation
 
 	data, err := search.searchLK(request.Name + " " + request.Singer)
-	if err != nil || len(data) == 0 {
-		return result
-	}
-
-	for _, reqDat := range data {
-		for _, song := range reqDat.Data.Song.ItemList {
-			// We use endpoint 2 for QRC lyrics
-			lyricsURL := fmt.Sprintf("https://c.y.qq.com/qqmusic/fcgi-bin/lyric_download.fcg?musicid=%s&version=15&miniversion=82&lrctype=4", song.ID)
-			
-			headers := map[string]string{
-				"Referer": "y.qq.com/portal/player.html",
-				"User-Agent": "Mozilla/5.0",
-			}
-
-			// We shouldn't use apputils.HttpGet because it returns JSON, and this endpoint might return raw XML (since it's QRC with <content> tags)
-			req, _ := http.NewRequest("GET", lyricsURL, nil)
-			for k, v := range headers {
-				req.Header.Set(k, v)
-			}
-			res, errReq := apputils.C.Do(req)
-			if errReq != nil {
-				continue
-			}
-			
-			bodyBytes, _ := io.ReadAll(res.Body)
-			res.Body.Close()
-			bodyString := string(bodyBytes)
-			
-			bodyString = strings.ReplaceAll(bodyString, "<!--", "")
-			bodyString = strings.ReplaceAll(bodyString, "-->", "")
-
-			// We need to parse <content>xxxx</content> out using regex
-			re := regexp.MustCompile(`<content>(.*?)</content>`)
-			matches := re.FindStringSubmatch(bodyString)
-			if len(matches) < 2 {
-				continue
-			}
-
-			qrcHex := matches[1]
-			decrypted, errD := decryptQQMusicQrc(qrcHex)
-			if errD != nil {
-				continue
-			}
-			
-			// Try to get <contentts> for trans
-			reTs := regexp.MustCompile(`<contentts>(.*?)</contentts>`)
-			matchesTs := reTs.FindStringSubmatch(bodyString)
-			transDecrypted := ""
-			if len(matchesTs) >= 2 {
-				transDec, errDecT := decryptQQMusicQrc(matchesTs[1])
-				if errDecT == nil {
-					transDecrypted = transDec
-				}
-			}
-
-			result = append(result, model.MusicRelation{
-				Name:   song.Name,
-				Singer: song.Singer,
-				Lid:    song.Mid,
-				Sid:    request.Id,
-				Lyrics: base64.StdEncoding.EncodeToString([]byte(decrypted)),
-				Trans:  transDecrypted,
-				Type:   QQMusicLKType,
-				Offset: 0,
-			})
+	if err != nil {
+		return result, fmt.Errorf("Failed Search QQ Music (LK) [%s - %s]: %w", request.Name, request.Singer, err)
+	}
+
+	for _, song := range data.Data.Song.ItemList {
+		lyrics, err := search.lyrics(song.ID, song.Mid)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		result = append(result, model.MusicRelation{
+			Name:   song.Name,
+			Singer: song.Singer,
+			Lid:    song.Mid,
+			Sid:    request.Id,
+			Lyrics: base64.StdEncoding.EncodeToString([]byte(lyrics.Lyrics)),
+			Trans:  encodeOptional(lyrics.Trans),
+			Roma:   encodeOptional(lyrics.Roma),
+			Type:   QQMusicLKType,
+			Offset: 0,
+		})
+	}
+	return result, nil
+}
+
+// lyrics 优先逐字 QRC, 没有时回退到 fcg_query_lyric_new.fcg 的 LRC
+func (search QQMusicLK) lyrics(id string, mid string) (qqLKLyrics, error) {
+	songId, err := search.songId(id, mid)
+	if err == nil {
+		lyrics, err := search.lyricsLK(songId)
+		if err == nil {
+			return lyrics, nil
 		}
+		log.Printf("[INFO] QQ Music QRC Unavailable [%s], Fallback To LRC: %s", mid, err)
+	} else {
+		log.Printf("[INFO] QQ Music Song ID Unavailable [%s], Fallback To LRC: %s", mid, err)
+	}
+
+	lrc, err := QQMusicLyrics{}.lyrics(mid)
+	if err != nil {
+		return qqLKLyrics{}, err
+	}
+	return qqLKLyrics{
+		Lyrics: decodeOptional(lrc.Lyric),
+		Trans:  decodeOptional(lrc.Trans),
+	}, nil
+}
+
+func encodeOptional(content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(content))
+}
+
+func decodeOptional(content string) string {
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return ""
 	}
-	return result
+	return string(decoded)
 }
@@ -15,21 +15,25 @@ import (
 type QQMusicLyrics struct {
 }
 
+var qqMusicClient = app_utils.Client("QQMusicLyrics")
+
 var searchBaseUrl = "https://c.y.qq.com/splcloud/fcgi-bin/smartbox_new.fcg?key=%s"
 var lyricsBaseUrl = "https://c.y.qq.com/lyric/fcgi-bin/fcg_query_lyric_new.fcg?songmid=%s&g_tk=5381&format=json"
 
-func (search QQMusicLyrics) Lyrics(request model.SearchRequest) []model.MusicRelation {
+func (search QQMusicLyrics) Lyrics(request model.SearchRequest) ([]model.MusicRelation, error) {
 	var result []model.MusicRelation
 
 	var itemList []model.QQMusicItem
 
-	data, done := search.search(request.Name + " " + request.Singer)
-	if !done {
+	// 所有搜索都失败时才算上游不可用
+	data, searchErr := search.search(request.Name + " " + request.Singer)
+	if searchErr == nil {
 		itemList = append(itemList, data.Data.Song.Itemlist...)
 	}
-	data, done = search.search(request.Name)
-	if !done {
+	data, err := search.search(request.Name)
+	if err == nil {
 		itemList = append(itemList, data.Data.Song.Itemlist...)
+		searchErr = nil
 	}
 
 	split := []string{"(", "（", "-", "《"}
@@ -40,11 +44,15 @@ func (search QQMusicLyrics) Lyrics(request model.SearchRequest) []model.MusicRel
 		}
 	}
 	if minIndex != len(request.Name) {
-		data, done = search.search(request.Name[:minIndex])
-		if !done {
+		data, err = search.search(request.Name[:minIndex])
+		if err == nil {
 			itemList = append(itemList, data.Data.Song.Itemlist...)
+			searchErr = nil
 		}
 	}
+	if searchErr != nil {
+		return result, searchErr
+	}
 
 	for _, song := range itemList {
 		lyrics, err := search.lyrics(song.Mid)
@@ -64,31 +72,29 @@ func (search QQMusicLyrics) Lyrics(request model.SearchRequest) []model.MusicRel
 			Offset: 0,
 		})
 	}
-	return result
+	return result, nil
 }
 
-func (search QQMusicLyrics) search(source string) (model.QQMusicSearch, bool) {
+func (search QQMusicLyrics) search(source string) (model.QQMusicSearch, error) {
 	key, err := app_utils.T2s(source)
 	if err != nil {
 		log.Printf(fmt.Sprintf("[ERROR] T2s Failed [%s] %v", source, err))
 		key = source
 	}
-	data, err := app_utils.HttpGet[model.QQMusicSearch](fmt.Sprintf(searchBaseUrl, url.QueryEscape(key)), map[string]string{})
+	data, err := app_utils.HttpGet[model.QQMusicSearch](qqMusicClient, fmt.Sprintf(searchBaseUrl, url.QueryEscape(key)), map[string]string{})
 	if err != nil {
-		log.Printf("[ERROR] Failed GET QQ Music Response!")
-		return model.QQMusicSearch{}, true
+		return model.QQMusicSearch{}, fmt.Errorf("failed GET QQ Music search: %w", err)
 	}
 
 	if data.Code != 0 {
-		log.Printf("Search QQMusic API Error Code " + strconv.Itoa(data.Code) + " Subcode " + strconv.Itoa(data.Subcode))
-		return model.QQMusicSearch{}, true
+		return model.QQMusicSearch{}, errors.New("Search QQMusic API Error Code " + strconv.Itoa(data.Code) + " Subcode " + strconv.Itoa(data.Subcode))
 	}
-	return data, false
+	return data, nil
 }
 
 func (search QQMusicLyrics) lyrics(mid string) (model.QQMusicLyrics, error) {
 	headers := map[string]string{"referer": "https://y.qq.com/portal/player.html"}
-	data, err := app_utils.HttpGet[model.QQMusicLyrics](fmt.Sprintf(lyricsBaseUrl, mid), headers)
+	data, err := app_utils.HttpGet[model.QQMusicLyrics](qqMusicClient, fmt.Sprintf(lyricsBaseUrl, mid), headers)
 	if err != nil {
 		return model.QQMusicLyrics{}, errors.New(fmt.Sprintf("[ERROR] Failed Get QQMusic Lyrics [%s - %s]: %s", mid, lyricsBaseUrl, err))
 	}
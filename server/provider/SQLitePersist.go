@@ -2,6 +2,7 @@ package provider
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"lyrics/model"
@@ -21,10 +22,17 @@ func persistProvider() sqlitePersist {
 	persist := sqlitePersist{
 		path: path,
 	}
-	return persist.lyricsTable()
+	return persist.lyricsTable().usersTable()
 }
 
-func (persist sqlitePersist) Lyrics(request model.SearchRequest) []model.MusicRelation {
+// Lyrics 优先返回用户自己确认的歌词, 没有时回退到共享的默认选择
+func (persist sqlitePersist) Lyrics(request model.SearchRequest, userId int64) []model.MusicRelation {
+	if userId > 0 {
+		if result := persist.userLyrics(request, userId); len(result) > 0 {
+			return result
+		}
+	}
+
 	var result []model.MusicRelation
 
 	db, err := sql.Open("sqlite", persist.path)
@@ -36,7 +44,7 @@ func (persist sqlitePersist) Lyrics(request model.SearchRequest) []model.MusicRe
 	}(db)
 
 	search := `
-      select relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_type, offset from lyrics_relation where spotify_id = ?
+      select relation_id, name, singer, lyrics_content, lyrics_trans, coalesce(lyrics_roma, ''), lyrics_type, offset, coalesce(credits, ''), coalesce(estimated, 0) from lyrics_relation where spotify_id = ?
 	`
 
 	row, err := db.Query(search, request.Id)
@@ -51,9 +59,12 @@ func (persist sqlitePersist) Lyrics(request model.SearchRequest) []model.MusicRe
 		var singer string
 		var lyrics string
 		var trans string
+		var roma string
 		var lyricsType string
 		var offset int64
-		err := row.Scan(&mid, &name, &singer, &lyrics, &trans, &lyricsType, &offset)
+		var credits string
+		var estimated bool
+		err := row.Scan(&mid, &name, &singer, &lyrics, &trans, &roma, &lyricsType, &offset, &credits, &estimated)
 		if err != nil {
 			log.Printf("[ERROR] Failed Scan Row %s", err)
 			continue
@@ -64,16 +75,25 @@ func (persist sqlitePersist) Lyrics(request model.SearchRequest) []model.MusicRe
 			Lid:    mid,
 			Sid:    request.Id,
 			// 获取歌词
-			Lyrics: lyrics,
-			Trans:  trans,
-			Type:   lyricsType,
-			Offset: offset,
+			Lyrics:    lyrics,
+			Trans:     trans,
+			Roma:      roma,
+			Type:      lyricsType,
+			Offset:    offset,
+			Credits:   decodeCredits(credits),
+			Estimated: estimated,
 		})
 	}
 	return result
 }
 
-func (persist sqlitePersist) Upsert(result model.MusicRelation) {
+// Upsert 匿名请求更新共享的默认选择, 登录用户只更新自己的选择
+func (persist sqlitePersist) Upsert(result model.MusicRelation, userId int64) {
+	if userId > 0 {
+		persist.userUpsert(result, userId)
+		return
+	}
+
 	db, err := sql.Open("sqlite", persist.path)
 	if err != nil {
 		log.Fatal(err)
@@ -84,18 +104,46 @@ func (persist sqlitePersist) Upsert(result model.MusicRelation) {
 
 	insert := `
 		INSERT OR REPLACE INTO lyrics_relation 
-		    (spotify_id, relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_type)
+		    (spotify_id, relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_roma, lyrics_type, credits, offset, estimated)
 		VALUES 
-			(?, ?, ?, ?, ?, ?, ?)
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = db.Exec(insert, result.Sid, result.Lid, result.Name, result.Singer, result.Lyrics, result.Trans, result.Type)
+	_, err = db.Exec(insert, result.Sid, result.Lid, result.Name, result.Singer, result.Lyrics, result.Trans, result.Roma, result.Type, encodeCredits(result.Credits), result.Offset, result.Estimated)
 	if err != nil {
 		log.Printf(fmt.Sprintf("[ERROR] Failed Insert/Update %s", err))
 	}
 }
 
-func (persist sqlitePersist) Offset(offset model.MusicRelationOffset) {
+// Init 仅在还没有默认选择时写入共享表, 不覆盖其他人已经确认的结果
+func (persist sqlitePersist) Init(result model.MusicRelation) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	insert := `
+		INSERT OR IGNORE INTO lyrics_relation 
+		    (spotify_id, relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_roma, lyrics_type, credits, offset, estimated)
+		VALUES 
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = db.Exec(insert, result.Sid, result.Lid, result.Name, result.Singer, result.Lyrics, result.Trans, result.Roma, result.Type, encodeCredits(result.Credits), result.Offset, result.Estimated)
+	if err != nil {
+		log.Printf("[ERROR] Failed Insert %s", err)
+	}
+}
+
+func (persist sqlitePersist) Offset(offset model.MusicRelationOffset, userId int64) {
+	if userId > 0 {
+		persist.userOffset(offset, userId)
+		return
+	}
+
 	db, err := sql.Open("sqlite", persist.path)
 	if err != nil {
 		log.Fatal(err)
@@ -105,7 +153,9 @@ func (persist sqlitePersist) Offset(offset model.MusicRelationOffset) {
 	}(db)
 	updateOffset := `update lyrics_relation set offset = ? where spotify_id = ? and relation_id = ?`
 	_, err = db.Exec(updateOffset, offset.Offset, offset.Sid, offset.Lid)
-	panic(err)
+	if err != nil {
+		panic(err)
+	}
 }
 
 // 不管有没有用都先初始化表结构
@@ -140,14 +190,79 @@ func (persist sqlitePersist) lyricsTable() sqlitePersist {
 				singer         text,
 				lyrics_content TEXT,
 				lyrics_trans   TEXT,
+				lyrics_roma    TEXT,
 				lyrics_type    TEXT,
+				credits        TEXT,
+				estimated      integer default 0,
 				offset integer default 0,
 				created_at     TIMESTAMP default CURRENT_TIMESTAMP
 			);
+			-- 纯音乐 / 没有找到歌词, retry_at (unix 秒) 之前不再搜索
+			create table if not exists lyrics_status
+			(
+				spotify_id TEXT primary key,
+				status     TEXT,
+				retry_at   integer,
+				created_at TIMESTAMP default CURRENT_TIMESTAMP
+			);
 	`
 	_, err = db.Exec(lyricsDB)
 	if err != nil {
 		log.Fatal(err)
 	}
+	addColumn(db, "lyrics_relation", "lyrics_roma", "TEXT")
+	addColumn(db, "lyrics_relation", "credits", "TEXT")
+	addColumn(db, "lyrics_relation", "estimated", "integer default 0")
 	return persist
 }
+
+// 署名信息以 JSON 保存
+func encodeCredits(credits *model.LyricCredits) string {
+	if credits == nil {
+		return ""
+	}
+	data, _ := json.Marshal(credits)
+	return string(data)
+}
+
+func decodeCredits(content string) *model.LyricCredits {
+	if content == "" {
+		return nil
+	}
+	var credits model.LyricCredits
+	if err := json.Unmarshal([]byte(content), &credits); err != nil {
+		log.Printf("[ERROR] Failed Decode Credits %s", err)
+		return nil
+	}
+	return &credits
+}
+
+// addColumn 旧版本创建的表缺少新增的列时补上
+func addColumn(db *sql.DB, table string, column string, definition string) {
+	rows, err := db.Query(fmt.Sprintf("pragma table_info(%s)", table))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var cid int
+		var name, columnType string
+		var notNull, pk int
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			log.Fatal(err)
+		}
+		if name == column {
+			return
+		}
+	}
+	_ = rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("alter table %s add column %s %s", table, column, definition)); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("[INFO] Added Column %s.%s", table, column)
+}
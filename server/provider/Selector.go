@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var selectorSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[(?:\*|-?\d+)\])*)$`)
+var selectorIndex = regexp.MustCompile(`\[(\*|-?\d+)\]`)
+
+// selectPath 按 a.b[0].c / items[*].name 选择 JSON 值, [*] 会展开数组, 负数下标从末尾开始
+func selectPath(data any, path string) []any {
+	path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+	current := []any{data}
+	if path == "" {
+		return current
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		m := selectorSegment.FindStringSubmatch(segment)
+		if m == nil {
+			return nil
+		}
+		var next []any
+		for _, value := range current {
+			if m[1] != "" {
+				object, ok := value.(map[string]any)
+				if !ok {
+					continue
+				}
+				if value, ok = object[m[1]]; !ok {
+					continue
+				}
+			}
+			next = append(next, selectIndexes(value, selectorIndex.FindAllStringSubmatch(m[2], -1))...)
+		}
+		current = next
+	}
+	return current
+}
+
+func selectIndexes(value any, indexes [][]string) []any {
+	values := []any{value}
+	for _, index := range indexes {
+		var next []any
+		for _, v := range values {
+			array, ok := v.([]any)
+			if !ok {
+				continue
+			}
+			if index[1] == "*" {
+				next = append(next, array...)
+				continue
+			}
+			i, _ := strconv.Atoi(index[1])
+			if i < 0 {
+				i += len(array)
+			}
+			if i >= 0 && i < len(array) {
+				next = append(next, array[i])
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// selectString 选择第一个匹配的值并转为字符串, 没有匹配时返回空字符串
+func selectString(data any, path string) string {
+	if path == "" {
+		return ""
+	}
+	values := selectPath(data, path)
+	if len(values) == 0 {
+		return ""
+	}
+	switch v := values[0].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
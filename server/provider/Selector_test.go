@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSelectPath(t *testing.T) {
+	var data any
+	_ = json.Unmarshal([]byte(`{
+		"data": {"songs": [
+			{"id": 1, "name": "晴天", "artists": [{"name": "周杰伦"}, {"name": "某人"}]},
+			{"id": 2, "name": "七里香", "artists": []}
+		]},
+		"count": 2,
+		"matrix": [[1, 2], [3, 4]],
+		"text": "plain"
+	}`), &data)
+
+	cases := []struct {
+		name, path string
+		want       []any
+	}{
+		{"root", "", []any{data}},
+		{"dollar prefix", "$.count", []any{2.0}},
+		{"nested key", "data.songs[0].name", []any{"晴天"}},
+		{"negative index", "data.songs[-1].id", []any{2.0}},
+		{"wildcard", "data.songs[*].id", []any{1.0, 2.0}},
+		{"nested wildcard", "data.songs[*].artists[*].name", []any{"周杰伦", "某人"}},
+		{"multiple indexes", "matrix[1][0]", []any{3.0}},
+		{"wildcard of wildcard", "matrix[*][1]", []any{2.0, 4.0}},
+		// 没有的键和越界的下标不匹配
+		{"missing key", "data.albums", nil},
+		{"missing nested key", "data.songs[*].album", nil},
+		{"index out of range", "data.songs[5]", nil},
+		{"negative out of range", "data.songs[-3]", nil},
+		{"empty array", "data.songs[1].artists[0]", nil},
+		// 类型不符时不匹配
+		{"key on array", "data.songs.name", nil},
+		{"index on object", "data[0]", nil},
+		{"key on string", "text.length", nil},
+		{"index on number", "count[0]", nil},
+		{"invalid index", "data.songs[x]", nil},
+		{"unclosed index", "data.songs[0", nil},
+	}
+	for _, c := range cases {
+		if got := selectPath(data, c.path); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: selectPath(%q) = %#v, want %#v", c.name, c.path, got, c.want)
+		}
+	}
+}
+
+func TestSelectString(t *testing.T) {
+	var data any
+	_ = json.Unmarshal([]byte(`{"id": 1234567, "score": 1.5, "big": 1e20, "ok": true, "none": null, "list": [1, "a"], "name": "晴天"}`), &data)
+	for path, want := range map[string]string{
+		"name":    "晴天",
+		"id":      "1234567",
+		"score":   "1.5",
+		"big":     "100000000000000000000",
+		"ok":      "true",
+		"none":    "",
+		"list":    `[1,"a"]`,
+		"list[1]": "a",
+		"missing": "",
+		"":        "",
+	} {
+		if got := selectString(data, path); got != want {
+			t.Errorf("selectString(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
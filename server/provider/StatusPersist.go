@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"database/sql"
+	"log"
+	"lyrics/model"
+	"time"
+)
+
+// Status 返回还没到重试时间的纯音乐 / 没有歌词状态
+func (persist sqlitePersist) Status(sid string) (model.LyricsStatus, bool) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	status := model.LyricsStatus{Sid: sid}
+	var retryAt int64
+	err = db.QueryRow(`select status, retry_at from lyrics_status where spotify_id = ? and retry_at > ?`, sid, time.Now().Unix()).Scan(&status.Status, &retryAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("[ERROR] Failed Get Lyrics Status %s", err)
+		}
+		return status, false
+	}
+	status.RetryAt = time.Unix(retryAt, 0).UTC().Format(time.RFC3339)
+	return status, true
+}
+
+func (persist sqlitePersist) SetStatus(sid string, status string, retry time.Duration) model.LyricsStatus {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	retryAt := time.Now().Add(retry)
+	_, err = db.Exec(`INSERT OR REPLACE INTO lyrics_status (spotify_id, status, retry_at) VALUES (?, ?, ?)`, sid, status, retryAt.Unix())
+	if err != nil {
+		log.Printf("[ERROR] Failed Save Lyrics Status %s", err)
+	}
+	return model.LyricsStatus{Sid: sid, Status: status, RetryAt: retryAt.UTC().Format(time.RFC3339)}
+}
+
+// ClearStatus 用户确认或上传歌词后清除
+func (persist sqlitePersist) ClearStatus(sid string) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	if _, err := db.Exec(`delete from lyrics_status where spotify_id = ?`, sid); err != nil {
+		log.Printf("[ERROR] Failed Clear Lyrics Status %s", err)
+	}
+}
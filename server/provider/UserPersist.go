@@ -0,0 +1,359 @@
+package provider
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"lyrics/model"
+)
+
+// Confirmed 用户自己确认或上传的歌词, 不回退到共享的默认选择; 没有用户 (匿名 / 管理员 token) 时为空
+func (persist sqlitePersist) Confirmed(sid string, userId int64) []model.MusicRelation {
+	if userId <= 0 {
+		return nil
+	}
+	return persist.userLyrics(model.SearchRequest{Id: sid}, userId)
+}
+
+func (persist sqlitePersist) userLyrics(request model.SearchRequest, userId int64) []model.MusicRelation {
+	var result []model.MusicRelation
+
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	search := `
+      select relation_id, name, singer, lyrics_content, lyrics_trans, coalesce(lyrics_roma, ''), lyrics_type, offset, coalesce(credits, ''), coalesce(estimated, 0) from user_lyrics_relation where user_id = ? and spotify_id = ?
+	`
+
+	row, err := db.Query(search, userId, request.Id)
+	if err != nil {
+		log.Printf("[ERROR] Failed Get User Persist %s", err)
+		return result
+	}
+	defer func(row *sql.Rows) {
+		_ = row.Close()
+	}(row)
+
+	for row.Next() {
+		relation := model.MusicRelation{Sid: request.Id}
+		var credits string
+		err := row.Scan(&relation.Lid, &relation.Name, &relation.Singer, &relation.Lyrics, &relation.Trans, &relation.Roma, &relation.Type, &relation.Offset, &credits, &relation.Estimated)
+		if err != nil {
+			log.Printf("[ERROR] Failed Scan Row %s", err)
+			continue
+		}
+		relation.Credits = decodeCredits(credits)
+		result = append(result, relation)
+	}
+	return result
+}
+
+func (persist sqlitePersist) userUpsert(result model.MusicRelation, userId int64) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	insert := `
+		INSERT OR REPLACE INTO user_lyrics_relation
+		    (user_id, spotify_id, relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_roma, lyrics_type, credits, offset, estimated)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = db.Exec(insert, userId, result.Sid, result.Lid, result.Name, result.Singer, result.Lyrics, result.Trans, result.Roma, result.Type, encodeCredits(result.Credits), result.Offset, result.Estimated)
+	if err != nil {
+		log.Printf("[ERROR] Failed User Insert/Update %s", err)
+	}
+}
+
+// 用户还没确认过时, 先把共享的默认选择复制一份再修改 offset, 避免影响其他人
+func (persist sqlitePersist) userOffset(offset model.MusicRelationOffset, userId int64) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	copyDefault := `
+		INSERT OR IGNORE INTO user_lyrics_relation
+		    (user_id, spotify_id, relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_roma, lyrics_type, credits, offset, estimated)
+		SELECT ?, spotify_id, relation_id, name, singer, lyrics_content, lyrics_trans, lyrics_roma, lyrics_type, credits, offset, estimated
+		FROM lyrics_relation WHERE spotify_id = ? and relation_id = ?
+	`
+	if _, err = db.Exec(copyDefault, userId, offset.Sid, offset.Lid); err != nil {
+		panic(err)
+	}
+
+	updateOffset := `update user_lyrics_relation set offset = ? where user_id = ? and spotify_id = ? and relation_id = ?`
+	if _, err = db.Exec(updateOffset, offset.Offset, userId, offset.Sid, offset.Lid); err != nil {
+		panic(err)
+	}
+}
+
+// Authenticate 根据明文 token 查找用户, 已吊销的 token 视为无效
+func (persist sqlitePersist) Authenticate(token string) (model.User, bool) {
+	var user model.User
+
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	search := `
+		select u.id, u.name, u.is_admin, u.created_at, u.mask_profanity from user_tokens t join users u on u.id = t.user_id
+		where t.token_hash = ? and t.revoked_at is null
+	`
+	var mask sql.NullBool
+	err = db.QueryRow(search, hashToken(token)).Scan(&user.Id, &user.Name, &user.Admin, &user.CreatedAt, &mask)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[ERROR] Failed Authenticate %s", err)
+		}
+		return user, false
+	}
+	user.MaskProfanity = nullBool(mask)
+	return user, true
+}
+
+func (persist sqlitePersist) Users() []model.User {
+	var result []model.User
+
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	search := `
+		select u.id, u.name, u.is_admin, u.created_at, u.mask_profanity, count(t.id) from users u
+		left join user_tokens t on t.user_id = u.id and t.revoked_at is null
+		group by u.id order by u.id
+	`
+	row, err := db.Query(search)
+	if err != nil {
+		panic(err)
+	}
+	defer func(row *sql.Rows) {
+		_ = row.Close()
+	}(row)
+
+	for row.Next() {
+		var user model.User
+		var mask sql.NullBool
+		if err := row.Scan(&user.Id, &user.Name, &user.Admin, &user.CreatedAt, &mask, &user.Tokens); err != nil {
+			log.Printf("[ERROR] Failed Scan Row %s", err)
+			continue
+		}
+		user.MaskProfanity = nullBool(mask)
+		result = append(result, user)
+	}
+	return result
+}
+
+func (persist sqlitePersist) CreateUser(request model.CreateUserRequest) model.User {
+	if request.Name == "" {
+		panic("user name is required")
+	}
+
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	res, err := db.Exec(`insert into users (name, is_admin) values (?, ?)`, request.Name, request.Admin)
+	if err != nil {
+		panic(fmt.Sprintf("Failed Create User [%s]: %s", request.Name, err))
+	}
+	id, _ := res.LastInsertId()
+
+	user := model.User{Id: id, Name: request.Name, Admin: request.Admin}
+	_ = db.QueryRow(`select created_at from users where id = ?`, id).Scan(&user.CreatedAt)
+	return user
+}
+
+func (persist sqlitePersist) CreateToken(userId int64) model.UserToken {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	token := "lk_" + hex.EncodeToString(raw)
+
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	var exists int
+	if err := db.QueryRow(`select count(1) from users where id = ?`, userId).Scan(&exists); err != nil || exists == 0 {
+		panic(fmt.Sprintf("user %d not found", userId))
+	}
+
+	insert := `insert into user_tokens (user_id, token_hash, prefix) values (?, ?, ?)`
+	res, err := db.Exec(insert, userId, hashToken(token), token[:10])
+	if err != nil {
+		panic(err)
+	}
+	id, _ := res.LastInsertId()
+
+	result := model.UserToken{Id: id, UserId: userId, Prefix: token[:10], Token: token}
+	_ = db.QueryRow(`select created_at from user_tokens where id = ?`, id).Scan(&result.CreatedAt)
+	return result
+}
+
+func (persist sqlitePersist) Tokens(userId int64) []model.UserToken {
+	var result []model.UserToken
+
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	row, err := db.Query(`select id, user_id, prefix, created_at, coalesce(revoked_at, '') from user_tokens where user_id = ? order by id`, userId)
+	if err != nil {
+		panic(err)
+	}
+	defer func(row *sql.Rows) {
+		_ = row.Close()
+	}(row)
+
+	for row.Next() {
+		var token model.UserToken
+		if err := row.Scan(&token.Id, &token.UserId, &token.Prefix, &token.CreatedAt, &token.RevokedAt); err != nil {
+			log.Printf("[ERROR] Failed Scan Row %s", err)
+			continue
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+func (persist sqlitePersist) RevokeToken(id int64) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	res, err := db.Exec(`update user_tokens set revoked_at = CURRENT_TIMESTAMP where id = ? and revoked_at is null`, id)
+	if err != nil {
+		panic(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		panic(fmt.Sprintf("token %d not found or already revoked", id))
+	}
+}
+
+// SaveSettings 保存用户的偏好设置, 为空的字段恢复为使用配置的默认值
+func (persist sqlitePersist) SaveSettings(userId int64, settings model.UserSettings) {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	if _, err := db.Exec(`update users set mask_profanity = ? where id = ?`, settings.MaskProfanity, userId); err != nil {
+		panic(err)
+	}
+}
+
+func nullBool(value sql.NullBool) *bool {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Bool
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (persist sqlitePersist) usersTable() sqlitePersist {
+	db, err := sql.Open("sqlite", persist.path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	usersDB := `
+			create table if not exists users
+			(
+				id         INTEGER primary key autoincrement,
+				name       TEXT not null unique,
+				is_admin   integer default 0,
+				-- 是否屏蔽脏话, null 表示使用配置的默认值
+				mask_profanity integer,
+				created_at TIMESTAMP default CURRENT_TIMESTAMP
+			);
+			create table if not exists user_tokens
+			(
+				id         INTEGER primary key autoincrement,
+				user_id    INTEGER not null references users (id),
+				token_hash TEXT not null unique,
+				prefix     TEXT,
+				created_at TIMESTAMP default CURRENT_TIMESTAMP,
+				revoked_at TIMESTAMP
+			);
+			create table if not exists user_lyrics_relation
+			(
+				user_id        INTEGER not null,
+				spotify_id     TEXT not null,
+				relation_id    TEXT,
+				name           text,
+				singer         text,
+				lyrics_content TEXT,
+				lyrics_trans   TEXT,
+				lyrics_roma    TEXT,
+				lyrics_type    TEXT,
+				credits        TEXT,
+				estimated      integer default 0,
+				offset integer default 0,
+				created_at     TIMESTAMP default CURRENT_TIMESTAMP,
+				primary key (user_id, spotify_id)
+			);
+	`
+	_, err = db.Exec(usersDB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	addColumn(db, "user_lyrics_relation", "lyrics_roma", "TEXT")
+	addColumn(db, "user_lyrics_relation", "credits", "TEXT")
+	addColumn(db, "user_lyrics_relation", "estimated", "integer default 0")
+	addColumn(db, "users", "mask_profanity", "integer")
+	return persist
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"lyrics/model"
+	"strings"
+	"testing"
+)
+
+// Confirmed 只返回用户自己的歌词, 不回退到共享的默认选择
+func TestConfirmed(t *testing.T) {
+	Persist.Upsert(model.MusicRelation{Sid: "confirmed", Lid: "shared", Type: "QQ Music", Lyrics: "c2hhcmVk"}, 0)
+	if got := Persist.Confirmed("confirmed", 0); len(got) != 0 {
+		t.Errorf("no user: %+v", got)
+	}
+	user := Persist.CreateUser(model.CreateUserRequest{Name: "confirmed"})
+	if got := Persist.Confirmed("confirmed", user.Id); len(got) != 0 {
+		t.Errorf("nothing confirmed: %+v", got)
+	}
+	Persist.Upsert(model.MusicRelation{Sid: "confirmed", Lid: "mine", Type: "QQ Music", Lyrics: "bWluZQ==", Offset: 300}, user.Id)
+	if got := Persist.Confirmed("confirmed", user.Id); len(got) != 1 || got[0].Lid != "mine" || got[0].Offset != 300 {
+		t.Errorf("confirmed: %+v", got)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	user := Persist.CreateUser(model.CreateUserRequest{Name: "tokens"})
+	token := Persist.CreateToken(user.Id)
+	if !strings.HasPrefix(token.Token, "lk_") || token.Prefix != token.Token[:10] || token.UserId != user.Id {
+		t.Fatalf("token = %+v", token)
+	}
+	other := Persist.CreateToken(user.Id)
+
+	if got, ok := Persist.Authenticate(token.Token); !ok || got.Id != user.Id || got.Name != "tokens" || got.Admin {
+		t.Errorf("Authenticate = %+v, %v", got, ok)
+	}
+	for _, invalid := range []string{"", "lk_unknown", token.Prefix} {
+		if _, ok := Persist.Authenticate(invalid); ok {
+			t.Errorf("Authenticate(%q) succeeded", invalid)
+		}
+	}
+
+	// 吊销后只有这个 token 失效, 列表中仍保留并带有吊销时间, 明文不再返回
+	Persist.RevokeToken(token.Id)
+	if _, ok := Persist.Authenticate(token.Token); ok {
+		t.Error("revoked token authenticated")
+	}
+	if _, ok := Persist.Authenticate(other.Token); !ok {
+		t.Error("other token was revoked")
+	}
+	tokens := Persist.Tokens(user.Id)
+	if len(tokens) != 2 || tokens[0].RevokedAt == "" || tokens[1].RevokedAt != "" || tokens[0].Token != "" {
+		t.Errorf("tokens = %+v", tokens)
+	}
+	for _, u := range Persist.Users() {
+		if u.Id == user.Id && u.Tokens != 1 {
+			t.Errorf("active tokens = %d, want 1", u.Tokens)
+		}
+	}
+
+	panics := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: no panic", name)
+			}
+		}()
+		f()
+	}
+	panics("revoke twice", func() { Persist.RevokeToken(token.Id) })
+	panics("unknown user", func() { Persist.CreateToken(user.Id + 1000) })
+	panics("duplicate user", func() { Persist.CreateUser(model.CreateUserRequest{Name: "tokens"}) })
+}
+
+// 用户的选择和 offset 保存在自己的行, 共享的默认选择和其他用户不受影响
+func TestUserSelections(t *testing.T) {
+	request := model.SearchRequest{Id: "selections"}
+	Persist.Upsert(model.MusicRelation{Sid: "selections", Lid: "shared", Type: "QQ Music", Lyrics: "c2hhcmVk"}, 0)
+	a := Persist.CreateUser(model.CreateUserRequest{Name: "selections a"})
+	b := Persist.CreateUser(model.CreateUserRequest{Name: "selections b"})
+
+	Persist.Upsert(model.MusicRelation{Sid: "selections", Lid: "mine", Type: "custom", Lyrics: "bWluZQ=="}, a.Id)
+	Persist.Offset(model.MusicRelationOffset{Sid: "selections", Lid: "shared", Offset: 500}, b.Id)
+
+	lid := func(userId int64) (string, int64) {
+		t.Helper()
+		result := Persist.Lyrics(request, userId)
+		if len(result) != 1 {
+			t.Fatalf("user %d: %d results", userId, len(result))
+		}
+		return result[0].Lid, result[0].Offset
+	}
+	if got, offset := lid(0); got != "shared" || offset != 0 {
+		t.Errorf("shared = %s %d, want shared 0", got, offset)
+	}
+	if got, offset := lid(a.Id); got != "mine" || offset != 0 {
+		t.Errorf("user a = %s %d, want mine 0", got, offset)
+	}
+	// 修改 offset 时先复制共享的默认选择
+	if got, offset := lid(b.Id); got != "shared" || offset != 500 {
+		t.Errorf("user b = %s %d, want shared 500", got, offset)
+	}
+	if confirmed := Persist.Confirmed("selections", b.Id); len(confirmed) != 1 || confirmed[0].Offset != 500 {
+		t.Errorf("user b confirmed = %+v", confirmed)
+	}
+
+	// 匿名修改的是共享的行, 已经有自己行的用户不受影响
+	Persist.Offset(model.MusicRelationOffset{Sid: "selections", Lid: "shared", Offset: -200}, 0)
+	if _, offset := lid(0); offset != -200 {
+		t.Errorf("shared offset = %d, want -200", offset)
+	}
+	if _, offset := lid(b.Id); offset != 500 {
+		t.Errorf("user b offset = %d, want 500", offset)
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 超过这个时间没有访问的桶会被清理, 避免按 IP 计数时无限增长
+const idleTimeout = 10 * time.Minute
+
+// Bucket 令牌桶, rate 为每秒补充的令牌数, burst 为桶容量
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func NewBucket(rate float64, burst int) *Bucket {
+	return newBucket(rate, burst, time.Now)
+}
+
+func newBucket(rate float64, burst int, now func() time.Time) *Bucket {
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now(), now: now}
+}
+
+// Take 尝试取一个令牌, 失败时返回需要等待的时间
+func (b *Bucket) Take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.rate <= 0 {
+		return false, time.Hour
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *Bucket) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Sub(b.last) > idleTimeout
+}
+
+// Limiter 按 key (IP / 用户) 分别维护令牌桶
+type Limiter struct {
+	rate    float64
+	burst   int
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: burst, buckets: map[string]*Bucket{}, swept: time.Now(), now: time.Now}
+}
+
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.bucket(key).Take()
+}
+
+func (l *Limiter) bucket(key string) *Bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) > idleTimeout {
+		for k, b := range l.buckets {
+			if b.idle(now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(l.rate, l.burst, l.now)
+		l.buckets[key] = b
+	}
+	return b
+}
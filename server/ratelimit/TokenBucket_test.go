@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBucket(2, 3, func() time.Time { return now })
+
+	take := func(step string, ok bool, wait time.Duration) {
+		t.Helper()
+		if gotOk, gotWait := b.Take(); gotOk != ok || gotWait != wait {
+			t.Errorf("%s: Take = %v, %s, want %v, %s", step, gotOk, gotWait, ok, wait)
+		}
+	}
+
+	// 桶满时可以连续取 burst 个
+	for i := 0; i < 3; i++ {
+		take("burst", true, 0)
+	}
+	take("empty", false, 500*time.Millisecond)
+
+	// 按 rate 补充, 不足一个时返回剩余等待时间
+	now = now.Add(250 * time.Millisecond)
+	take("half token", false, 250*time.Millisecond)
+	now = now.Add(250 * time.Millisecond)
+	take("refilled", true, 0)
+	take("empty again", false, 500*time.Millisecond)
+
+	// 长时间空闲后最多补满 burst
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		take("capped", true, 0)
+	}
+	take("capped empty", false, 500*time.Millisecond)
+}
+
+func TestBucketNoRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBucket(0, 1, func() time.Time { return now })
+	if ok, _ := b.Take(); !ok {
+		t.Fatal("first take failed")
+	}
+	now = now.Add(time.Hour)
+	if ok, wait := b.Take(); ok || wait != time.Hour {
+		t.Errorf("Take = %v, %s, want false, 1h", ok, wait)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(1, 2)
+	l.now = func() time.Time { return now }
+	l.swept = now
+
+	allow := func(step, key string, ok bool) {
+		t.Helper()
+		if got, _ := l.Allow(key); got != ok {
+			t.Errorf("%s: Allow(%s) = %v, want %v", step, key, got, ok)
+		}
+	}
+
+	allow("burst", "a", true)
+	allow("burst", "a", true)
+	allow("empty", "a", false)
+	// 不同 key 互不影响
+	allow("other key", "b", true)
+	allow("other key", "b", true)
+	allow("other key empty", "b", false)
+
+	now = now.Add(time.Second)
+	allow("refilled", "a", true)
+	allow("refilled empty", "a", false)
+
+	// 空闲超过 idleTimeout 的桶被清理, 再次访问时重新装满
+	now = now.Add(idleTimeout + time.Second)
+	allow("after idle", "c", true)
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("idle bucket a was not swept")
+	}
+	allow("new bucket", "a", true)
+	allow("new bucket", "a", true)
+	allow("new bucket empty", "a", false)
+}
+
+func TestLimiterMinBurst(t *testing.T) {
+	l := New(1, 0)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("burst 0 should allow one request")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("second request allowed")
+	}
+}
@@ -0,0 +1,45 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Interaction 一次上游请求和响应, Body 为原始字节 (JSON 中为 base64), 加密的 QRC/KRC/eapi 内容原样保存
+type Interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// 请求体, 只在同一 URL 有多条记录时用于区分
+	RequestBody []byte              `json:"request_body,omitempty"`
+	Status      int                 `json:"status"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body"`
+}
+
+// Cassette 一个 fixture 文件中的全部请求
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+func Load(path string) (Cassette, error) {
+	var cassette Cassette
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cassette, err
+	}
+	err = json.Unmarshal(content, &cassette)
+	return cassette, err
+}
+
+func (c Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
@@ -0,0 +1,61 @@
+package replay
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// Recorder 把经过的请求和响应追加写入 Path, 用于录制 fixture
+type Recorder struct {
+	Base http.RoundTripper
+	Path string
+
+	mu       sync.Mutex
+	cassette Cassette
+	loaded   bool
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			requestBody, _ = io.ReadAll(body)
+			_ = body.Close()
+		}
+	}
+
+	resp, err := r.Base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.loaded {
+		// 追加到已有的 fixture, 同一个文件可以分多次录制
+		r.cassette, _ = Load(r.Path)
+		r.loaded = true
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: requestBody,
+		Status:      resp.StatusCode,
+		Header:      resp.Header.Clone(),
+		Body:        body,
+	})
+	if err := r.cassette.Save(r.Path); err != nil {
+		log.Printf("[ERROR] Failed Save Fixture %s: %s", r.Path, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+package replaytest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"lyrics/replay"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// 重定向到回放服务器时保存原始 URL 的请求头
+const originalURL = "X-Replay-URL"
+
+// Server 用 httptest 回放 fixture, 同一个 URL 有多条记录时优先匹配请求体, 否则按录制顺序返回
+type Server struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	used []bool
+	c    replay.Cassette
+}
+
+// NewServer 加载 fixture 并启动回放服务器, 测试结束时自动关闭
+func NewServer(t testing.TB, path string) *Server {
+	t.Helper()
+	cassette, err := replay.Load(path)
+	if err != nil {
+		t.Fatalf("load fixture %s: %s", path, err)
+	}
+	s := &Server{c: cassette, used: make([]bool, len(cassette.Interactions))}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Transport 把所有请求转发到回放服务器, 原始地址放在 X-Replay-URL 中
+func (s *Server) Transport() http.RoundTripper {
+	target, _ := url.Parse(s.URL)
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		r := req.Clone(req.Context())
+		r.Header.Set(originalURL, req.URL.String())
+		r.URL.Scheme = target.Scheme
+		r.URL.Host = target.Host
+		r.Host = target.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	original := r.Header.Get(originalURL)
+
+	interaction, ok := s.match(r.Method, original, body)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no fixture for %s %s", r.Method, original), http.StatusNotFound)
+		return
+	}
+	for k, values := range interaction.Header {
+		if k == "Content-Length" || k == "Content-Encoding" || k == "Transfer-Encoding" {
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(interaction.Status)
+	_, _ = w.Write(interaction.Body)
+}
+
+func (s *Server) match(method string, target string, body []byte) (replay.Interaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidate := -1
+	for i, interaction := range s.c.Interactions {
+		if interaction.Method != method || interaction.URL != target {
+			continue
+		}
+		if interaction.RequestBody != nil && bytes.Equal(interaction.RequestBody, body) {
+			candidate = i
+			break
+		}
+		if candidate < 0 || (s.used[candidate] && !s.used[i]) {
+			candidate = i
+		}
+	}
+	if candidate < 0 {
+		return replay.Interaction{}, false
+	}
+	s.used[candidate] = true
+	return s.c.Interactions[candidate], true
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,151 @@
+package roman
+
+const (
+	hangulFirst = 0xAC00
+	hangulLast  = 0xD7A3
+)
+
+// 国语罗马字 (Revised Romanization) 的初声 / 中声 / 终声
+var (
+	initials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	medials  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	finals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+// 初声和终声的下标
+const (
+	initialG  = 0
+	initialN  = 2
+	initialD  = 3
+	initialR  = 5
+	initialM  = 6
+	initialO  = 11
+	initialJ  = 12
+	initialCh = 14
+	initialK  = 15
+	initialT  = 16
+
+	finalN  = 4
+	finalNh = 6
+	finalL  = 8
+	finalLh = 15
+	finalM  = 16
+	finalNg = 21
+	finalH  = 27
+
+	medialI = 20
+)
+
+// liaison 后一个字以 ㅇ 开头时终声移到后一个字: 剩下的终声, 移过去的初声
+var liaison = map[int][2]int{
+	1: {0, 0}, 2: {0, 1}, 3: {1, 9}, 4: {0, 2}, 5: {4, 12}, 6: {0, 2}, 7: {0, 3}, 8: {0, 5},
+	9: {8, 0}, 10: {8, 6}, 11: {8, 7}, 12: {8, 9}, 13: {8, 16}, 14: {8, 17}, 15: {0, 5},
+	16: {0, 6}, 17: {0, 7}, 18: {17, 10}, 19: {0, 9}, 20: {0, 10}, 22: {0, 12}, 23: {0, 14},
+	24: {0, 15}, 25: {0, 16}, 26: {0, 17}, 27: {0, 11},
+}
+
+type syllable struct {
+	initial, medial, final int
+}
+
+// hangul 谚文按字转换, 连在一起的谚文是一个词, 词内的字之间不加空格
+func hangul(runes []rune, mode string) []token {
+	var result []token
+	for i := 0; i < len(runes); {
+		if !isHangul(runes[i]) {
+			j := i
+			for j < len(runes) && !isHangul(runes[j]) {
+				j++
+			}
+			var rest []token
+			other(runes[i:j], func(k int, r rune) (token, bool) {
+				return hanToken(k, r, mode)
+			}, &rest)
+			for _, t := range rest {
+				t.start += i
+				result = append(result, t)
+			}
+			i = j
+			continue
+		}
+
+		j := i
+		var word []syllable
+		for j < len(runes) && isHangul(runes[j]) {
+			n := int(runes[j] - hangulFirst)
+			word = append(word, syllable{n / 588, n % 588 / 28, n % 28})
+			j++
+		}
+		for k, text := range pronounce(word) {
+			result = append(result, token{start: i + k, text: text, glue: k > 0})
+		}
+		i = j
+	}
+	return result
+}
+
+// pronounce 按发音规则处理相邻字: 连音, 鼻音化, 流音化, ㅎ 送气和腭化
+func pronounce(word []syllable) []string {
+	for k := 0; k+1 < len(word); k++ {
+		cur, next := &word[k], &word[k+1]
+		if cur.final == 0 {
+			continue
+		}
+		switch {
+		case next.initial == initialO && cur.final != finalNg:
+			move := liaison[cur.final]
+			cur.final, next.initial = move[0], move[1]
+			if next.medial == medialI && next.initial == initialD {
+				next.initial = initialJ
+			} else if next.medial == medialI && next.initial == initialT {
+				next.initial = initialCh
+			}
+		case (cur.final == finalH || cur.final == finalNh || cur.final == finalLh) && aspirated(next.initial) != next.initial:
+			next.initial = aspirated(next.initial)
+			cur.final = map[int]int{finalH: 0, finalNh: finalN, finalLh: finalL}[cur.final]
+		case next.initial == initialR && (cur.final == finalN || cur.final == finalL):
+			cur.final, next.initial = finalL, -1
+		case next.initial == initialN && cur.final == finalL:
+			next.initial = -1
+		case next.initial == initialN || next.initial == initialM || next.initial == initialR:
+			switch finals[cur.final] {
+			case "k":
+				cur.final = finalNg
+			case "t":
+				cur.final = finalN
+			case "p":
+				cur.final = finalM
+			}
+			if next.initial == initialR && cur.final != finalL {
+				next.initial = initialN
+			}
+		}
+	}
+
+	result := make([]string, len(word))
+	for k, s := range word {
+		initial := "l"
+		if s.initial >= 0 {
+			initial = initials[s.initial]
+		}
+		result[k] = initial + medials[s.medial] + finals[s.final]
+	}
+	return result
+}
+
+// aspirated ㅎ 后的 ㄱ / ㄷ / ㅈ 变为送气音, 其余不变
+func aspirated(initial int) int {
+	switch initial {
+	case initialG:
+		return initialK
+	case initialD:
+		return initialT
+	case initialJ:
+		return initialCh
+	}
+	return initial
+}
+
+func isHangul(r rune) bool {
+	return r >= hangulFirst && r <= hangulLast
+}
@@ -0,0 +1,33 @@
+package roman
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// 内置的粤拼字表只覆盖歌词常用字, 不在表中的字原样保留
+//
+//go:embed jyutping.txt
+var jyutpingTable string
+
+var jyutpingDict = sync.OnceValue(func() map[rune]string {
+	dict := map[rune]string{}
+	for _, line := range strings.Split(jyutpingTable, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, r := range fields[1] {
+			dict[r] = fields[0]
+		}
+	}
+	return dict
+})
+
+func cantonese(r rune) string {
+	if j, ok := jyutpingDict()[r]; ok {
+		return j
+	}
+	return string(r)
+}
@@ -0,0 +1,15 @@
+package roman
+
+import (
+	"github.com/mozillazg/go-pinyin"
+)
+
+var pinyinArgs = pinyin.Args{Style: pinyin.Tone}
+
+// mandarin 带声调的拼音, 多音字取最常用的读音
+func mandarin(r rune) string {
+	if p := pinyin.SinglePinyin(r, pinyinArgs); len(p) > 0 {
+		return p[0]
+	}
+	return string(r)
+}
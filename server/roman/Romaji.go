@@ -0,0 +1,259 @@
+package roman
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ikawaha/kagome-dict/ipa"
+	"github.com/ikawaha/kagome/v2/tokenizer"
+)
+
+// 分词器和 IPA 词典 (汉字读音) 第一次遇到日语时再加载
+var japanese = sync.OnceValue(func() *tokenizer.Tokenizer {
+	t, err := tokenizer.New(ipa.Dict(), tokenizer.OmitBosEos())
+	if err != nil {
+		log.Printf("[ERROR] Failed Load Japanese Dictionary %s", err)
+		return nil
+	}
+	return t
+})
+
+// romaji 分词后按读音转为平文式罗马字, 词之间加空格; 汉字的读音归到第一个汉字, 送假名仍按字对齐
+func romaji(runes []rune) []token {
+	t := japanese()
+	if t == nil {
+		return han(runes, Pinyin)
+	}
+
+	// 整行的读音一起转换, 词尾的促音要看下一个词
+	words := t.Tokenize(string(runes))
+	readings := make([][]rune, len(words))
+	var kana []rune
+	for i, word := range words {
+		if symbol(word) {
+			continue
+		}
+		reading, ok := word.Reading()
+		if !ok || reading == "*" {
+			reading = word.Surface
+		}
+		readings[i] = []rune(katakana(reading))
+		kana = append(kana, readings[i]...)
+	}
+	chars := kanaRomaji(kana)
+
+	var result []token
+	offset := 0
+	for i, word := range words {
+		surface := []rune(word.Surface)
+		if symbol(word) {
+			var rest []token
+			other(surface, func(int, rune) (token, bool) { return token{}, false }, &rest)
+			for _, r := range rest {
+				r.start += word.Start
+				result = append(result, r)
+			}
+			continue
+		}
+
+		wordChars := chars[offset : offset+len(readings[i])]
+		offset += len(readings[i])
+		if text, ok := particleRomaji(word); ok {
+			wordChars = []string{text}
+		}
+		glue := attached(word)
+		for _, s := range alignReading(surface, readings[i]) {
+			text := strings.Join(wordChars[min(s.from, len(wordChars)):min(s.to, len(wordChars))], "")
+			if text == "" {
+				continue
+			}
+			result = append(result, token{start: word.Start + s.start, text: text, glue: glue})
+			glue = true
+		}
+	}
+	return result
+}
+
+// attached 助动词和接续助词 て / で 接在前一个词后面, 不加空格
+func attached(word tokenizer.Token) bool {
+	pos := word.POS()
+	switch {
+	case len(pos) == 0:
+		return false
+	case pos[0] == "助動詞":
+		return true
+	case len(pos) > 1 && pos[0] == "助詞" && pos[1] == "接続助詞":
+		return true
+	case len(pos) > 1 && pos[0] == "動詞" && (pos[1] == "非自立" || pos[1] == "接尾"):
+		return true
+	}
+	return false
+}
+
+// symbol 标点和空白原样保留
+func symbol(word tokenizer.Token) bool {
+	if pos := word.POS(); len(pos) > 0 && pos[0] == "記号" {
+		return true
+	}
+	for _, r := range word.Surface {
+		if unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// particleRomaji 助词 は / へ / を 按发音写作 wa / e / o
+func particleRomaji(word tokenizer.Token) (string, bool) {
+	if pos := word.POS(); len(pos) == 0 || pos[0] != "助詞" {
+		return "", false
+	}
+	switch word.Surface {
+	case "は":
+		return "wa", true
+	case "へ":
+		return "e", true
+	case "を":
+		return "o", true
+	}
+	return "", false
+}
+
+// reading 从词中第 start 个字开始的一段, 对应读音的 [from, to)
+type reading struct {
+	start    int
+	from, to int
+}
+
+// alignReading 把读音对应到词的各个字: 假名逐字对应, 连续的汉字作为一段, 先匹配两头的送假名再分配中间的读音
+func alignReading(surface []rune, kana []rune) []reading {
+	var head []reading
+	i := 0
+	for i < len(surface) && i < len(kana) && isKana(surface[i]) && katakanaRune(surface[i]) == kana[i] {
+		head = append(head, reading{start: i, from: i, to: i + 1})
+		i++
+	}
+	var tail []reading
+	j, k := len(surface), len(kana)
+	for j > i && k > i && isKana(surface[j-1]) && katakanaRune(surface[j-1]) == kana[k-1] {
+		j--
+		k--
+		tail = append([]reading{{start: j, from: k, to: k + 1}}, tail...)
+	}
+	if i < j && i < k {
+		head = append(head, reading{start: i, from: i, to: k})
+	}
+	return append(head, tail...)
+}
+
+// kanaRomaji 逐字转换片假名, 拗音归到前一个字, 促音写成后一个音的辅音, 长音重复前一个元音
+func kanaRomaji(kana []rune) []string {
+	result := make([]string, len(kana))
+	for i := 0; i < len(kana); i++ {
+		if i+1 < len(kana) {
+			if digraph, ok := kanaDigraphs[string(kana[i:i+2])]; ok {
+				result[i] = digraph
+				i++
+				continue
+			}
+		}
+		if romaji, ok := kanaTable[kana[i]]; ok {
+			result[i] = romaji
+		} else if !isKana(kana[i]) {
+			result[i] = string(kana[i])
+		}
+	}
+
+	for i, r := range kana {
+		switch r {
+		case 'ッ':
+			if i+1 < len(kana) && result[i+1] != "" {
+				next := result[i+1]
+				if strings.HasPrefix(next, "ch") {
+					result[i] = "t"
+				} else if !strings.ContainsRune("aeiou", rune(next[0])) {
+					result[i] = next[:1]
+				}
+			}
+		case 'ー':
+			for p := i - 1; p >= 0; p-- {
+				if result[p] != "" {
+					if last := result[p][len(result[p])-1]; strings.IndexByte("aeiou", last) >= 0 {
+						result[i] = string(last)
+					}
+					break
+				}
+			}
+		case 'ン':
+			result[i] = "n"
+		}
+	}
+	return result
+}
+
+func katakana(text string) string {
+	return string(katakanaRunes([]rune(text)))
+}
+
+func katakanaRunes(runes []rune) []rune {
+	result := make([]rune, len(runes))
+	for i, r := range runes {
+		result[i] = katakanaRune(r)
+	}
+	return result
+}
+
+// katakanaRune 平假名转片假名
+func katakanaRune(r rune) rune {
+	if r >= 'ぁ' && r <= 'ゖ' {
+		return r + 0x60
+	}
+	return r
+}
+
+func isKana(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana)
+}
+
+var kanaTable = map[rune]string{
+	'ア': "a", 'イ': "i", 'ウ': "u", 'エ': "e", 'オ': "o",
+	'カ': "ka", 'キ': "ki", 'ク': "ku", 'ケ': "ke", 'コ': "ko",
+	'サ': "sa", 'シ': "shi", 'ス': "su", 'セ': "se", 'ソ': "so",
+	'タ': "ta", 'チ': "chi", 'ツ': "tsu", 'テ': "te", 'ト': "to",
+	'ナ': "na", 'ニ': "ni", 'ヌ': "nu", 'ネ': "ne", 'ノ': "no",
+	'ハ': "ha", 'ヒ': "hi", 'フ': "fu", 'ヘ': "he", 'ホ': "ho",
+	'マ': "ma", 'ミ': "mi", 'ム': "mu", 'メ': "me", 'モ': "mo",
+	'ヤ': "ya", 'ユ': "yu", 'ヨ': "yo",
+	'ラ': "ra", 'リ': "ri", 'ル': "ru", 'レ': "re", 'ロ': "ro",
+	'ワ': "wa", 'ヰ': "i", 'ヱ': "e", 'ヲ': "o",
+	'ガ': "ga", 'ギ': "gi", 'グ': "gu", 'ゲ': "ge", 'ゴ': "go",
+	'ザ': "za", 'ジ': "ji", 'ズ': "zu", 'ゼ': "ze", 'ゾ': "zo",
+	'ダ': "da", 'ヂ': "ji", 'ヅ': "zu", 'デ': "de", 'ド': "do",
+	'バ': "ba", 'ビ': "bi", 'ブ': "bu", 'ベ': "be", 'ボ': "bo",
+	'パ': "pa", 'ピ': "pi", 'プ': "pu", 'ペ': "pe", 'ポ': "po",
+	'ヴ': "vu",
+	'ァ': "a", 'ィ': "i", 'ゥ': "u", 'ェ': "e", 'ォ': "o",
+	'ャ': "ya", 'ュ': "yu", 'ョ': "yo", 'ヮ': "wa",
+}
+
+// 拗音和外来语的组合
+var kanaDigraphs = map[string]string{
+	"キャ": "kya", "キュ": "kyu", "キョ": "kyo",
+	"シャ": "sha", "シュ": "shu", "ショ": "sho", "シェ": "she",
+	"チャ": "cha", "チュ": "chu", "チョ": "cho", "チェ": "che",
+	"ニャ": "nya", "ニュ": "nyu", "ニョ": "nyo",
+	"ヒャ": "hya", "ヒュ": "hyu", "ヒョ": "hyo",
+	"ミャ": "mya", "ミュ": "myu", "ミョ": "myo",
+	"リャ": "rya", "リュ": "ryu", "リョ": "ryo",
+	"ギャ": "gya", "ギュ": "gyu", "ギョ": "gyo",
+	"ジャ": "ja", "ジュ": "ju", "ジョ": "jo", "ジェ": "je",
+	"ヂャ": "ja", "ヂュ": "ju", "ヂョ": "jo",
+	"ビャ": "bya", "ビュ": "byu", "ビョ": "byo",
+	"ピャ": "pya", "ピュ": "pyu", "ピョ": "pyo",
+	"ファ": "fa", "フィ": "fi", "フェ": "fe", "フォ": "fo",
+	"ウィ": "wi", "ウェ": "we", "ウォ": "wo",
+	"ティ": "ti", "ディ": "di", "トゥ": "tu", "ドゥ": "du",
+	"ヴァ": "va", "ヴィ": "vi", "ヴェ": "ve", "ヴォ": "vo",
+}
@@ -0,0 +1,166 @@
+package roman
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+const (
+	// Auto 按文字判断: 含假名为日语, 含谚文为韩语, 其余汉字按普通话
+	Auto     = "auto"
+	Pinyin   = "pinyin"
+	Jyutping = "jyutping"
+	Off      = "off"
+)
+
+func Valid(mode string) bool {
+	switch mode {
+	case "", Auto, Pinyin, Jyutping, Off:
+		return true
+	}
+	return false
+}
+
+// token 一个罗马音单位, start 为在整行中的字符下标; glue 为 true 时与前一个单位之间不加空格
+type token struct {
+	start int
+	text  string
+	glue  bool
+}
+
+// Pieces 把一行的各个逐字片段一起转换 (整行语境用于日语分词和韩语连音), 返回与 texts 一一对应的罗马音,
+// 单位从哪个片段开始就归到哪个片段, 单位之间的空格放在前一个片段末尾
+func Pieces(texts []string, mode string) []string {
+	var runes []rune
+	var bounds []int
+	for _, text := range texts {
+		runes = append(runes, []rune(text)...)
+		bounds = append(bounds, len(runes))
+	}
+
+	result := make([]strings.Builder, len(texts))
+	piece := 0
+	last := -1
+	for _, t := range tokens(runes, mode) {
+		for piece < len(bounds)-1 && t.start >= bounds[piece] {
+			piece++
+		}
+		if last >= 0 && !t.glue {
+			result[last].WriteByte(' ')
+		}
+		result[piece].WriteString(t.text)
+		last = piece
+	}
+
+	pieces := make([]string, len(texts))
+	for i := range result {
+		pieces[i] = result[i].String()
+	}
+	return pieces
+}
+
+// Line 转换整行文字
+func Line(text string, mode string) string {
+	return Pieces([]string{text}, mode)[0]
+}
+
+func tokens(runes []rune, mode string) []token {
+	switch {
+	case hasKana(runes):
+		return romaji(runes)
+	case hasHangul(runes):
+		return hangul(runes, mode)
+	}
+	return han(runes, mode)
+}
+
+// han 汉字逐字转换, 其余文字原样保留
+func han(runes []rune, mode string) []token {
+	var result []token
+	other(runes, func(i int, r rune) (token, bool) {
+		return hanToken(i, r, mode)
+	}, &result)
+	return result
+}
+
+func hanToken(i int, r rune, mode string) (token, bool) {
+	if !unicode.Is(unicode.Han, r) {
+		return token{}, false
+	}
+	if mode == Jyutping {
+		return token{start: i, text: cantonese(r)}, true
+	}
+	return token{start: i, text: mandarin(r)}, true
+}
+
+// other 逐字处理, convert 不认识的文字按空白分成单词原样保留, 标点紧跟前一个单位
+func other(runes []rune, convert func(i int, r rune) (token, bool), result *[]token) {
+	word := -1
+	flush := func(end int) {
+		if word >= 0 {
+			*result = append(*result, token{start: word, text: string(runes[word:end])})
+			word = -1
+		}
+	}
+	for i, r := range runes {
+		if t, ok := convert(i, r); ok {
+			flush(i)
+			*result = append(*result, t)
+			continue
+		}
+		switch {
+		case unicode.IsSpace(r):
+			flush(i)
+		case unicode.IsPunct(r) && word < 0:
+			*result = append(*result, token{start: i, text: punct(r), glue: true})
+		case word < 0:
+			word = i
+		}
+	}
+	flush(len(runes))
+}
+
+// punct 全角标点转半角
+func punct(r rune) string {
+	switch r {
+	case '、', '，':
+		return ","
+	case '。', '｡':
+		return "."
+	case '「', '」', '『', '』', '“', '”':
+		return "\""
+	case '…':
+		return "..."
+	}
+	return width.Narrow.String(string(r))
+}
+
+func hasKana(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) && r != 'ー' && r != '・' {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHangul(runes []rune) bool {
+	for _, r := range runes {
+		if isHangul(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has 文字中是否有需要转换的汉字 / 假名 / 谚文
+func Has(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || isHangul(r) {
+			return true
+		}
+	}
+	return false
+}
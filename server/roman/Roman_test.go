@@ -0,0 +1,65 @@
+package roman
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	cases := []struct {
+		name, text, mode, want string
+	}{
+		// 拼音带声调, ü 保留
+		{"pinyin tones", "晴天", Pinyin, "qíng tiān"},
+		{"pinyin umlaut", "绿色", Pinyin, "lǜ sè"},
+		{"pinyin spaces", "你好 世界", Pinyin, "nǐ hǎo shì jiè"},
+		// 没有假名的汉字在 auto 模式按普通话
+		{"auto han", "東京", Auto, "dōng jīng"},
+		{"jyutping", "我愛你", Jyutping, "ngo5 oi3 nei5"},
+		// 不在粤拼字表中的字和拉丁字母原样保留
+		{"jyutping fallback", "愛鱻 abc", Jyutping, "oi3 鱻 abc"},
+		// 助词 は / へ / を 按读音
+		{"romaji particles", "私は学校へ行く", Auto, "watashi wa gakkou e iku"},
+		{"romaji particle o", "君を待っている", Auto, "kimi o matteiru"},
+		// 促音双写后面的辅音, ch 前写作 t
+		{"romaji sokuon", "ちょっと", Auto, "chotto"},
+		{"romaji long vowels", "お母さん", Auto, "okaasan"},
+		{"romaji katakana long vowels", "コーヒー", Auto, "koohii"},
+		{"romaji okurigana", "書きました", Auto, "kakimashita"},
+		// 连音: 终声移到 ㅇ 开头的下一个字
+		{"hangul liaison", "한국어", Auto, "hangugeo"},
+		{"hangul liaison single", "음악", Auto, "eumak"},
+		// 鼻音化和流音化
+		{"hangul nasalization", "국물", Auto, "gungmul"},
+		{"hangul nasalization final", "감사합니다", Auto, "gamsahamnida"},
+		{"hangul lateralization", "신라", Auto, "silla"},
+		// ㅎ 在元音之间不发音
+		{"hangul h", "좋아", Auto, "joa"},
+		{"hangul words", "사랑해 너를", Auto, "saranghae neoreul"},
+	}
+	for _, c := range cases {
+		if got := Line(c.text, c.mode); got != c.want {
+			t.Errorf("%s: Line(%q, %s) = %q, want %q", c.name, c.text, c.mode, got, c.want)
+		}
+	}
+}
+
+// 逐字片段按整行转换, 读音归到它开始的片段
+func TestPieces(t *testing.T) {
+	cases := []struct {
+		name  string
+		texts []string
+		want  []string
+	}{
+		{"okurigana", []string{"食べ", "て", "い", "る"}, []string{"tabe", "te", "i", "ru"}},
+		{"kanji with okurigana", []string{"書", "き", "ました"}, []string{"ka", "ki", "mashita"}},
+		{"sokuon piece", []string{"待", "っ", "て"}, []string{"ma", "t", "te"}},
+		{"hangul liaison", []string{"음", "악"}, []string{"eu", "mak"}},
+		{"pinyin", []string{"晴", "天"}, []string{"qíng ", "tiān"}},
+	}
+	for _, c := range cases {
+		if got := Pieces(c.texts, Auto); !slices.Equal(got, c.want) {
+			t.Errorf("%s: Pieces(%q) = %q, want %q", c.name, c.texts, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package route
+
+import (
+	apputils "lyrics/app-utils"
+	"lyrics/model"
+	"lyrics/provider"
+	"lyrics/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func users(c *gin.Context) {
+	response.Ok(provider.Persist.Users(), c)
+}
+
+// 创建用户的同时签发第一个 token
+func createUser(c *gin.Context) {
+	user := provider.Persist.CreateUser(apputils.FromGinPostJson[model.CreateUserRequest](c))
+	token := provider.Persist.CreateToken(user.Id)
+	user.Tokens = 1
+	response.Ok(gin.H{"user": user, "token": token}, c)
+}
+
+func userTokens(c *gin.Context) {
+	response.Ok(provider.Persist.Tokens(pathId(c)), c)
+}
+
+func createToken(c *gin.Context) {
+	request := apputils.FromGinPostJson[model.CreateTokenRequest](c)
+	response.Ok(provider.Persist.CreateToken(request.UserId), c)
+}
+
+func revokeToken(c *gin.Context) {
+	provider.Persist.RevokeToken(pathId(c))
+	response.Success(c)
+}
+
+func pathId(c *gin.Context) int64 {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
@@ -0,0 +1,82 @@
+package route
+
+import (
+	"crypto/subtle"
+	"lyrics/config"
+	"lyrics/model"
+	"lyrics/provider"
+	"lyrics/response"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const userKey = "user"
+
+// Auth 解析请求中的 token, 匿名用户 Id 为 0, 使用共享的默认选择
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := requestToken(c)
+		if token == "" {
+			if config.C.Auth.Required {
+				if limitedUnauthorized(c) {
+					return
+				}
+				response.Ret(http.StatusUnauthorized, "token is required", c)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		admin := config.C.Auth.AdminToken
+		if admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1 {
+			c.Set(userKey, model.User{Name: "admin", Admin: true})
+			c.Next()
+			return
+		}
+
+		user, ok := provider.Persist.Authenticate(token)
+		if !ok {
+			if limitedUnauthorized(c) {
+				return
+			}
+			response.Ret(http.StatusUnauthorized, "invalid token", c)
+			c.Abort()
+			return
+		}
+		c.Set(userKey, user)
+		c.Next()
+	}
+}
+
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !currentUser(c).Admin {
+			response.Ret(http.StatusForbidden, "admin only", c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func currentUser(c *gin.Context) model.User {
+	if user, ok := c.Get(userKey); ok {
+		return user.(model.User)
+	}
+	return model.User{}
+}
+
+// 支持 Authorization: Bearer, X-Lyrics-Token 以及 ?token= (方便 WebSocket/浏览器源)
+func requestToken(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token := c.GetHeader("X-Lyrics-Token"); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
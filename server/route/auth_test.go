@@ -0,0 +1,142 @@
+package route
+
+import (
+	"encoding/json"
+	"lyrics/config"
+	"lyrics/model"
+	"lyrics/provider"
+	"lyrics/response"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func authRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHolder())
+	group := r.Group("/api/v1")
+	group.Use(Auth())
+	group.POST("/lyrics/confirm", confirm)
+	group.POST("/lyrics/offset", offset)
+	// 返回解析出的用户, 只用于测试
+	group.GET("/whoami", func(c *gin.Context) { response.Ok(currentUser(c), c) })
+	admin := group.Group("/admin")
+	admin.Use(AdminOnly())
+	admin.GET("/users", users)
+	return r
+}
+
+// call 发送请求, header 为 Authorization / X-Lyrics-Token, 为空时不带 token
+func call(r *gin.Engine, method, target, header, token string, body any) *httptest.ResponseRecorder {
+	var payload string
+	if body != nil {
+		data, _ := json.Marshal(body)
+		payload = string(data)
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	switch header {
+	case "Authorization":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "X-Lyrics-Token":
+		req.Header.Set("X-Lyrics-Token", token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth(t *testing.T) {
+	admin, required := config.C.Auth.AdminToken, config.C.Auth.Required
+	config.C.Auth.AdminToken = "admin-secret"
+	defer func() { config.C.Auth.AdminToken, config.C.Auth.Required = admin, required }()
+
+	r := authRouter()
+	user := provider.Persist.CreateUser(model.CreateUserRequest{Name: "auth"})
+	token := provider.Persist.CreateToken(user.Id)
+	revoked := provider.Persist.CreateToken(user.Id)
+	provider.Persist.RevokeToken(revoked.Id)
+
+	cases := []struct {
+		name     string
+		target   string
+		header   string
+		token    string
+		required bool
+		code     int
+		// whoami 返回的用户 Id, 管理员 token 为 0
+		userId int64
+	}{
+		{"anonymous", "/api/v1/whoami", "", "", false, http.StatusOK, 0},
+		{"anonymous required", "/api/v1/whoami", "", "", true, http.StatusUnauthorized, 0},
+		{"bearer", "/api/v1/whoami", "Authorization", token.Token, true, http.StatusOK, user.Id},
+		{"header", "/api/v1/whoami", "X-Lyrics-Token", token.Token, true, http.StatusOK, user.Id},
+		{"query", "/api/v1/whoami?token=" + token.Token, "", "", true, http.StatusOK, user.Id},
+		{"admin", "/api/v1/whoami", "Authorization", "admin-secret", true, http.StatusOK, 0},
+		{"invalid", "/api/v1/whoami", "Authorization", "lk_unknown", false, http.StatusUnauthorized, 0},
+		{"revoked", "/api/v1/whoami", "Authorization", revoked.Token, false, http.StatusUnauthorized, 0},
+		{"user on admin", "/api/v1/admin/users", "Authorization", token.Token, false, http.StatusForbidden, 0},
+		{"anonymous on admin", "/api/v1/admin/users", "", "", false, http.StatusForbidden, 0},
+		{"admin on admin", "/api/v1/admin/users", "Authorization", "admin-secret", true, http.StatusOK, 0},
+	}
+	for _, c := range cases {
+		config.C.Auth.Required = c.required
+		w := call(r, http.MethodGet, c.target, c.header, c.token, nil)
+		if w.Code != c.code {
+			t.Errorf("%s: status %d, want %d: %s", c.name, w.Code, c.code, w.Body.String())
+			continue
+		}
+		if w.Code != http.StatusOK || !strings.HasPrefix(c.target, "/api/v1/whoami") {
+			continue
+		}
+		var vo struct {
+			Data model.User `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &vo); err != nil || vo.Data.Id != c.userId {
+			t.Errorf("%s: user = %+v, want id %d", c.name, vo.Data, c.userId)
+		}
+	}
+}
+
+// 通过接口确认 / 修改 offset 时, 每个用户写自己的行, 共享的默认选择不受影响
+func TestUserSelectionsRoute(t *testing.T) {
+	r := authRouter()
+	a := provider.Persist.CreateUser(model.CreateUserRequest{Name: "route a"})
+	b := provider.Persist.CreateUser(model.CreateUserRequest{Name: "route b"})
+	tokenA, tokenB := provider.Persist.CreateToken(a.Id), provider.Persist.CreateToken(b.Id)
+
+	shared := model.MusicRelation{Sid: "route-selections", Lid: "shared", Type: "custom", Lyrics: encode("[00:01.00]shared")}
+	if w := call(r, http.MethodPost, "/api/v1/lyrics/confirm", "", "", shared); w.Code != http.StatusOK {
+		t.Fatalf("anonymous confirm: %d %s", w.Code, w.Body.String())
+	}
+	mine := model.MusicRelation{Sid: "route-selections", Lid: "mine", Type: "custom", Lyrics: encode("[00:01.00]mine")}
+	if w := call(r, http.MethodPost, "/api/v1/lyrics/confirm", "Authorization", tokenA.Token, mine); w.Code != http.StatusOK {
+		t.Fatalf("user a confirm: %d %s", w.Code, w.Body.String())
+	}
+	move := model.MusicRelationOffset{Sid: "route-selections", Lid: "shared", Offset: 700}
+	if w := call(r, http.MethodPost, "/api/v1/lyrics/offset", "X-Lyrics-Token", tokenB.Token, move); w.Code != http.StatusOK {
+		t.Fatalf("user b offset: %d %s", w.Code, w.Body.String())
+	}
+
+	request := model.SearchRequest{Id: "route-selections"}
+	cases := []struct {
+		name   string
+		userId int64
+		lid    string
+		offset int64
+	}{
+		{"shared", 0, "shared", 0},
+		{"user a", a.Id, "mine", 0},
+		{"user b", b.Id, "shared", 700},
+	}
+	for _, c := range cases {
+		got := provider.Persist.Lyrics(request, c.userId)
+		if len(got) != 1 || got[0].Lid != c.lid || got[0].Offset != c.offset {
+			t.Errorf("%s: %+v, want %s offset %d", c.name, got, c.lid, c.offset)
+		}
+	}
+}
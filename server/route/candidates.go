@@ -0,0 +1,96 @@
+package route
+
+import (
+	"lyrics/model"
+	"sync"
+	"time"
+)
+
+const (
+	// 搜索结果保留多久, 用于确认时找回清理 / 屏蔽 / 转换前的原始内容
+	candidateTTL = time.Hour
+	// 最多保留的候选数, 超过时先去掉过期的, 仍然超过时任意去掉一条
+	maxCandidates = 4096
+)
+
+type candidateKey struct {
+	sid, kind, lid string
+}
+
+type cachedCandidate struct {
+	relation model.MusicRelation
+	expires  time.Time
+}
+
+// candidateCache 返回给客户端之前的原始候选, 确认时保存这里的内容而不是客户端回传的 (可能已经屏蔽或转换过的) 内容
+type candidateCache struct {
+	mu    sync.Mutex
+	items map[candidateKey]cachedCandidate
+	now   func() time.Time
+}
+
+var candidates = &candidateCache{items: map[candidateKey]cachedCandidate{}, now: time.Now}
+
+func keyOf(relation model.MusicRelation) candidateKey {
+	return candidateKey{sid: relation.Sid, kind: relation.Type, lid: relation.Lid}
+}
+
+func (cache *candidateCache) store(data []model.MusicRelation) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	now := cache.now()
+	for _, relation := range data {
+		if relation.Sid == "" {
+			continue
+		}
+		if len(cache.items) >= maxCandidates {
+			cache.evict(now)
+		}
+		cache.items[keyOf(relation)] = cachedCandidate{relation: relation, expires: now.Add(candidateTTL)}
+	}
+}
+
+func (cache *candidateCache) evict(now time.Time) {
+	for key, item := range cache.items {
+		if now.After(item.expires) {
+			delete(cache.items, key)
+		}
+	}
+	for key := range cache.items {
+		if len(cache.items) < maxCandidates {
+			return
+		}
+		delete(cache.items, key)
+	}
+}
+
+func (cache *candidateCache) find(relation model.MusicRelation) (model.MusicRelation, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	item, ok := cache.items[keyOf(relation)]
+	if !ok || cache.now().After(item.expires) {
+		return model.MusicRelation{}, false
+	}
+	return item.relation, true
+}
+
+// resolveConfirm 确认时要保存的内容: 优先使用搜索时缓存的原始候选, 其次是已经保存的同一条歌词, 只保留客户端设置的 offset;
+// 都找不到时, 返回前被转换过的内容不能保存, 其余 (用户上传的歌词 / raw 结果) 原样保存
+func resolveConfirm(relation model.MusicRelation, stored []model.MusicRelation) (model.MusicRelation, bool) {
+	original, ok := candidates.find(relation)
+	if !ok {
+		for _, candidate := range stored {
+			if candidate.Type == relation.Type && candidate.Lid == relation.Lid {
+				original, ok = candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return relation, !relation.Transformed
+	}
+	original.Sid = relation.Sid
+	original.Offset = relation.Offset
+	original.Transformed = false
+	return original, true
+}
@@ -0,0 +1,76 @@
+package route
+
+import (
+	"encoding/base64"
+	"lyrics/lyric"
+	"lyrics/model"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// Persist 在包初始化时会创建数据库文件
+	_ = os.Remove("lyrics.db")
+	os.Exit(code)
+}
+
+func encode(content string) string {
+	return base64.StdEncoding.EncodeToString([]byte(content))
+}
+
+// 确认时保存搜索到的原始内容, 不保存返回给客户端的屏蔽 / 转换后的内容
+func TestResolveConfirm(t *testing.T) {
+	original := model.MusicRelation{Sid: "s1", Type: "QQ Music", Lid: "1",
+		Lyrics: encode("[00:00.00]作词 : 某人\n[00:01.00]<00:01.00>fuck <00:01.50>you"), Roma: encode("[00:01.00]fuck you")}
+	candidates.store([]model.MusicRelation{original})
+
+	shown := view{mask: true, roma: "auto", script: "t"}.relation(original)
+	if !shown.Transformed || lyric.Decode(shown.Lyrics) == lyric.Decode(original.Lyrics) {
+		t.Fatalf("shown = %+v, want transformed", shown)
+	}
+	shown.Offset = 300
+	got, ok := resolveConfirm(shown, nil)
+	if !ok || got.Lyrics != original.Lyrics || got.Roma != original.Roma || got.Offset != 300 || got.Transformed {
+		t.Errorf("resolveConfirm = %+v, %v, want the original with offset 300", got, ok)
+	}
+
+	// 缓存过期后使用已经保存的同一条歌词
+	now := candidates.now
+	candidates.now = func() time.Time { return now().Add(2 * candidateTTL) }
+	defer func() { candidates.now = now }()
+	if got, ok := resolveConfirm(shown, []model.MusicRelation{original}); !ok || got.Lyrics != original.Lyrics {
+		t.Errorf("stored: resolveConfirm = %+v, %v", got, ok)
+	}
+	// 找不到原始内容时拒绝转换过的内容, 用户上传和 raw 结果原样保存
+	if _, ok := resolveConfirm(shown, nil); ok {
+		t.Error("expired: transformed lyrics accepted")
+	}
+	upload := model.MusicRelation{Sid: "s1", Type: "custom", Lid: "mine", Lyrics: encode("[00:01.00]hello")}
+	if got, ok := resolveConfirm(upload, nil); !ok || !reflect.DeepEqual(got, upload) {
+		t.Errorf("upload: resolveConfirm = %+v, %v", got, ok)
+	}
+}
+
+// 原文有词被屏蔽时 Provider 自带的罗马音按屏蔽后的原文重新生成, 罗马音中的脏话也会屏蔽
+func TestMaskRoma(t *testing.T) {
+	relation := model.MusicRelation{
+		Lyrics: encode("[00:01.00]クソったれ"),
+		Roma:   encode("[00:01.00]kusottare"),
+	}
+	shown := view{mask: true, raw: true}.relation(relation)
+	if roma := lyric.Decode(shown.Roma); roma == "[00:01.00]kusottare" || roma == "" {
+		t.Errorf("roma = %q, want regenerated from masked lyrics", roma)
+	}
+
+	relation = model.MusicRelation{
+		Lyrics: encode("[00:01.00]君が好き"),
+		Roma:   encode("[00:01.00]kimi ga suki shit"),
+	}
+	shown = view{mask: true, raw: true}.relation(relation)
+	if roma := lyric.Decode(shown.Roma); roma != "[00:01.00]kimi ga suki s**t" {
+		t.Errorf("roma = %q, want the provider romanization masked", roma)
+	}
+}
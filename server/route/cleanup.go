@@ -0,0 +1,80 @@
+package route
+
+import (
+	"encoding/base64"
+	"log"
+	"lyrics/config"
+	"lyrics/lyric"
+	"lyrics/model"
+	"maps"
+	"regexp"
+)
+
+var cleanOptions = newCleanOptions(config.C.Cleanup)
+
+func newCleanOptions(conf config.CleanupConfig) lyric.CleanOptions {
+	options := lyric.CleanOptions{Credits: conf.Credits, Blank: conf.Blank}
+	for _, pattern := range conf.Ads {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("[ERROR] Skip Invalid Cleanup Pattern %q: %s", pattern, err)
+			continue
+		}
+		options.Ads = append(options.Ads, re)
+	}
+	return options
+}
+
+// cleanRelation 清理原文 / 翻译 / 罗马音, 署名从原文和翻译中提取, 翻译中的 [by:] 作为翻译者
+func cleanRelation(relation model.MusicRelation) model.MusicRelation {
+	if !config.C.Cleanup.Enabled {
+		return relation
+	}
+	var credits, trans model.LyricCredits
+	if relation.Credits != nil {
+		credits = *relation.Credits
+		credits.Other = maps.Clone(credits.Other)
+	}
+	relation.Lyrics = cleanLayer(relation.Lyrics, &credits)
+	relation.Trans = cleanLayer(relation.Trans, &trans)
+	relation.Roma = cleanLayer(relation.Roma, nil)
+
+	trans.Translator, trans.Uploader = trans.Uploader, ""
+	mergeCredits(&credits, trans)
+	if credits.Uploader+credits.Translator+credits.Lyricist+credits.Composer+credits.Arranger+credits.Producer != "" || len(credits.Other) > 0 {
+		relation.Credits = &credits
+	}
+	return relation
+}
+
+func cleanLayer(content string, credits *model.LyricCredits) string {
+	if content == "" {
+		return content
+	}
+	cleaned := lyric.Clean(lyric.Decode(content), cleanOptions, credits)
+	return base64.StdEncoding.EncodeToString([]byte(cleaned))
+}
+
+// mergeCredits 用 other 补上 credits 中为空的字段
+func mergeCredits(credits *model.LyricCredits, other model.LyricCredits) {
+	for _, pair := range [][2]*string{
+		{&credits.Uploader, &other.Uploader},
+		{&credits.Translator, &other.Translator},
+		{&credits.Lyricist, &other.Lyricist},
+		{&credits.Composer, &other.Composer},
+		{&credits.Arranger, &other.Arranger},
+		{&credits.Producer, &other.Producer},
+	} {
+		if *pair[0] == "" {
+			*pair[0] = *pair[1]
+		}
+	}
+	for k, v := range other.Other {
+		if credits.Other == nil {
+			credits.Other = map[string]string{}
+		}
+		if _, ok := credits.Other[k]; !ok {
+			credits.Other[k] = v
+		}
+	}
+}
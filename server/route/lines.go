@@ -0,0 +1,44 @@
+package route
+
+import (
+	"lyrics/lyric"
+	"lyrics/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lyricsLines GET /lyrics/:sid/lines?tolerance=500, 返回原文和翻译 / 罗马音逐行配对后的结果, 时间未加 offset
+func lyricsLines(c *gin.Context) {
+	tolerance, err := strconv.ParseInt(c.DefaultQuery("tolerance", strconv.Itoa(lyric.BilingualTolerance)), 10, 64)
+	if err != nil || tolerance < 0 {
+		response.Ret(http.StatusBadRequest, "invalid tolerance", c)
+		return
+	}
+	v, ok := queryView(c)
+	if !ok {
+		return
+	}
+
+	relation, ok := storedLyrics(c.Param("sid"), currentUser(c).Id)
+	if !ok {
+		response.Ret(http.StatusNotFound, "lyrics not found, search it first", c)
+		return
+	}
+	relation = v.relation(relation)
+	lines := lyric.Bilingual(
+		lyric.Parse(lyric.Decode(relation.Lyrics)),
+		lyric.Parse(lyric.Decode(relation.Trans)),
+		lyric.Parse(lyric.Decode(relation.Roma)),
+		tolerance,
+	)
+	response.Ok(gin.H{
+		"sid":    relation.Sid,
+		"lid":    relation.Lid,
+		"name":   relation.Name,
+		"singer": relation.Singer,
+		"offset": relation.Offset,
+		"lines":  lines,
+	}, c)
+}
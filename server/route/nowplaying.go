@@ -0,0 +1,161 @@
+package route
+
+import (
+	"fmt"
+	"log"
+	apputils "lyrics/app-utils"
+	"lyrics/model"
+	"lyrics/playback"
+	"lyrics/provider"
+	"lyrics/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var hub = playback.NewHub()
+
+// nowPlaying 播放端上报当前歌曲和进度, 换歌时在后台解析歌词并推送给跟随者
+func nowPlaying(c *gin.Context) {
+	request := apputils.FromGinPostJson[model.NowPlayingRequest](c)
+	if request.Id == "" {
+		response.Ret(http.StatusBadRequest, "id is required", c)
+		return
+	}
+	user := currentUser(c)
+	room, err := hub.Room(roomOwner(user), roomName(request.Room))
+	if err != nil {
+		response.Ret(http.StatusBadRequest, err.Error(), c)
+		return
+	}
+
+	state := playback.NowPlaying{
+		Sid:    request.Id,
+		Name:   request.Name,
+		Singer: request.Singer,
+		Clock:  playback.Clock{Position: request.Position, Rate: request.Rate, Playing: request.Playing, At: time.Now()},
+	}
+	resolve := false
+	if current := room.State(); current.Sid == request.Id {
+		// 同一首歌只更新进度, 歌词可能还在解析中
+		state.Relation = current.Relation
+	} else if relation, ok := storedLyrics(request.Id, user.Id); ok {
+		state.Relation = &relation
+	} else if status, ok := provider.Persist.Status(request.Id); ok {
+		log.Printf("[INFO] Now Playing Has No Lyrics [%s - %s]: %s", request.Name, request.Singer, status.Status)
+	} else {
+		if !allowSearch(c) {
+			return
+		}
+		resolve = true
+	}
+	room.Publish(state)
+
+	if resolve {
+		go func() {
+			data, _ := searchLyrics(model.SearchRequest{Name: request.Name, Singer: request.Singer, Id: request.Id}, user)
+			if len(data) < 1 {
+				log.Printf("[INFO] Now Playing Lyrics Not Found [%s - %s]", request.Name, request.Singer)
+				return
+			}
+			// 用户可能有自己的选择, 以保存的结果为准
+			if relation, ok := storedLyrics(request.Id, user.Id); ok {
+				room.Resolve(request.Id, relation)
+				return
+			}
+			room.Resolve(request.Id, data[0])
+		}()
+	}
+	response.Ok(nowPlayingView(state, view{mask: masking(c, nil)}), c)
+}
+
+func currentPlaying(c *gin.Context) {
+	v, ok := queryView(c)
+	if !ok {
+		return
+	}
+	room, ok := hub.Find(roomOwner(currentUser(c)), roomName(c.Query("room")))
+	var state playback.NowPlaying
+	if ok {
+		state = room.State()
+	}
+	if state.Sid == "" {
+		response.Ret(http.StatusNotFound, "nothing playing", c)
+		return
+	}
+	response.Ok(nowPlayingView(state, v), c)
+}
+
+// nowPlayingEvents SSE 跟随房间, 推送 nowplaying / lyrics / line / word 事件
+func nowPlayingEvents(c *gin.Context) {
+	user := currentUser(c)
+	v, ok := queryView(c)
+	if !ok {
+		return
+	}
+	room, err := hub.Room(roomOwner(user), roomName(c.Query("room")))
+	if err != nil {
+		response.Ret(http.StatusBadRequest, err.Error(), c)
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	session := playback.NewSession(sseSink{c: c}, func(sid string) (model.MusicRelation, bool) {
+		return storedLyrics(sid, user.Id)
+	}, nil)
+	session.SetTransform(v.relation)
+	session.Follow(room)
+	go func() {
+		<-c.Request.Context().Done()
+		session.Close()
+	}()
+	session.Serve()
+}
+
+type sseSink struct {
+	c *gin.Context
+}
+
+func (sink sseSink) Write(event playback.Event) error {
+	sink.c.SSEvent(event.Type, event)
+	sink.c.Writer.Flush()
+	return sink.c.Request.Context().Err()
+}
+
+func (sink sseSink) Ping() error {
+	if _, err := fmt.Fprint(sink.c.Writer, ": ping\n\n"); err != nil {
+		return err
+	}
+	sink.c.Writer.Flush()
+	return nil
+}
+
+// 房间只在同一个用户内共享, 匿名用户共用一组房间
+func roomOwner(user model.User) string {
+	return strconv.FormatInt(user.Id, 10)
+}
+
+func roomName(room string) string {
+	if room == "" {
+		return "default"
+	}
+	return room
+}
+
+func nowPlayingView(state playback.NowPlaying, v view) gin.H {
+	result := gin.H{
+		"sid":      state.Sid,
+		"name":     state.Name,
+		"singer":   state.Singer,
+		"position": state.Position(),
+		"playing":  state.Clock.Playing,
+		"resolved": state.Relation != nil,
+	}
+	if state.Relation != nil {
+		result["lyrics"] = v.relation(*state.Relation)
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package route
+
+import (
+	"encoding/base64"
+	"lyrics/config"
+	"lyrics/lyric"
+	"lyrics/model"
+	"lyrics/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var masker = newMasker(config.C.Profanity)
+
+// newMasker 内置词表加上配置中追加的词
+func newMasker(conf config.ProfanityConfig) *lyric.Masker {
+	lists := lyric.DefaultProfanity()
+	for language, words := range conf.Words {
+		lists[language] = append(lists[language], words...)
+	}
+	return lyric.NewMasker(lists, conf.Languages, conf.Mask)
+}
+
+// masking 是否屏蔽脏话: 请求中指定的优先, 其次是用户设置, 最后是配置的默认值
+func masking(c *gin.Context, requested *bool) bool {
+	if requested != nil {
+		return *requested
+	}
+	if user := currentUser(c); user.MaskProfanity != nil {
+		return *user.MaskProfanity
+	}
+	return config.C.Profanity.Enabled
+}
+
+// queryMask 读取 ?mask=true / false, 不带时返回 nil
+func queryMask(c *gin.Context) (*bool, bool) {
+	value, ok := c.GetQuery("mask")
+	if !ok || value == "" {
+		return nil, true
+	}
+	mask, err := strconv.ParseBool(value)
+	if err != nil {
+		response.Ret(http.StatusBadRequest, "mask must be true or false", c)
+		return nil, false
+	}
+	return &mask, true
+}
+
+// maskRelation 屏蔽原文 / 翻译 / 罗马音中的脏话, 字数不变所以逐字时间不受影响.
+// 原文有词被屏蔽时去掉 Provider 自带的罗马音 (否则读音会露出被屏蔽的词), 由 romaRelation 按屏蔽后的原文重新生成
+func maskRelation(relation model.MusicRelation) model.MusicRelation {
+	lyrics := maskLayer(relation.Lyrics)
+	if lyric.Decode(lyrics) != lyric.Decode(relation.Lyrics) {
+		relation.Roma = ""
+	}
+	relation.Lyrics = lyrics
+	relation.Trans = maskLayer(relation.Trans)
+	relation.Roma = maskLayer(relation.Roma)
+	return relation
+}
+
+func maskLayer(content string) string {
+	if content == "" {
+		return content
+	}
+	return base64.StdEncoding.EncodeToString([]byte(masker.Mask(lyric.Decode(content))))
+}
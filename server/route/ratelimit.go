@@ -0,0 +1,107 @@
+package route
+
+import (
+	"fmt"
+	"lyrics/config"
+	"lyrics/ratelimit"
+	"lyrics/response"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	readLimiter                     = newLimiter(config.C.RateLimit.Read)
+	searchLimiter                   = newLimiter(config.C.RateLimit.Search)
+	allowIPs, allowNets, allowUsers = parseAllowlist(config.C.RateLimit.Allowlist)
+)
+
+func newLimiter(budget config.Budget) *ratelimit.Limiter {
+	return ratelimit.New(budget.PerMinute/60, budget.Burst)
+}
+
+// RateLimit 所有接口共用的读取预算
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limited(c, readLimiter) {
+			c.Next()
+		}
+	}
+}
+
+// allowSearch 需要请求上游前再消耗一次搜索预算, 超限时已经写好 429 响应
+func allowSearch(c *gin.Context) bool {
+	return !limited(c, searchLimiter)
+}
+
+func limited(c *gin.Context, limiter *ratelimit.Limiter) bool {
+	return limitedKey(c, limiter, clientKey(c))
+}
+
+// limitedUnauthorized 认证失败的请求在返回 401 前先按 IP 消耗读取预算, 猜 token 同样受限
+func limitedUnauthorized(c *gin.Context) bool {
+	return limitedKey(c, readLimiter, "ip:"+c.ClientIP())
+}
+
+func limitedKey(c *gin.Context, limiter *ratelimit.Limiter, key string) bool {
+	if !config.C.RateLimit.Enabled || allowed(c) {
+		return false
+	}
+	ok, wait := limiter.Allow(key)
+	if ok {
+		return false
+	}
+	retry := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retry))
+	response.Ret(http.StatusTooManyRequests, fmt.Sprintf("too many requests, retry after %s", time.Duration(retry)*time.Second), c)
+	c.Abort()
+	return true
+}
+
+// 登录用户按用户计数, 匿名请求按 IP 计数
+func clientKey(c *gin.Context) string {
+	if user := currentUser(c); user.Id > 0 {
+		return "user:" + strconv.FormatInt(user.Id, 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func allowed(c *gin.Context) bool {
+	user := currentUser(c)
+	if user.Admin || (user.Name != "" && allowUsers[user.Name]) {
+		return true
+	}
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil {
+		return false
+	}
+	if allowIPs[ip.String()] {
+		return true
+	}
+	for _, n := range allowNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAllowlist(entries []string) (map[string]bool, []*net.IPNet, map[string]bool) {
+	ips := map[string]bool{}
+	var nets []*net.IPNet
+	names := map[string]bool{}
+	for _, entry := range entries {
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			ips[ip.String()] = true
+		} else {
+			names[entry] = true
+		}
+	}
+	return ips, nets, names
+}
@@ -0,0 +1,43 @@
+package route
+
+import (
+	"encoding/base64"
+	"lyrics/lyric"
+	"lyrics/model"
+	"lyrics/response"
+	"lyrics/roman"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const romaUsage = "roma must be one of auto, pinyin, jyutping, off"
+
+// queryRoma 读取 ?roma=, 不支持的值返回 400
+func queryRoma(c *gin.Context) (string, bool) {
+	mode := c.Query("roma")
+	if !roman.Valid(mode) {
+		response.Ret(http.StatusBadRequest, romaUsage, c)
+		return "", false
+	}
+	return mode, true
+}
+
+// romaRelation 生成罗马音层, 逐字时间与原文一致; auto 时保留 Provider 自带的罗马音, 指定 pinyin / jyutping 时重新生成
+func romaRelation(relation model.MusicRelation, mode string) model.MusicRelation {
+	if mode == roman.Off || relation.Lyrics == "" {
+		return relation
+	}
+	if relation.Roma != "" && (mode == "" || mode == roman.Auto) {
+		return relation
+	}
+	text := lyric.Decode(relation.Lyrics)
+	if !roman.Has(text) {
+		return relation
+	}
+	roma := lyric.TransformPieces(text, func(texts []string) []string {
+		return roman.Pieces(texts, mode)
+	})
+	relation.Roma = base64.StdEncoding.EncodeToString([]byte(roma))
+	return relation
+}
@@ -2,10 +2,14 @@ package route
 
 import (
 	"fmt"
+	"log"
 	apputils "lyrics/app-utils"
+	"lyrics/config"
 	"lyrics/model"
 	"lyrics/provider"
 	"lyrics/response"
+	"lyrics/roman"
+	"net/http"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -14,64 +18,215 @@ import (
 func Run() {
 	r := gin.Default()
 	r.Use(ErrorHolder())
+	if err := r.SetTrustedProxies(config.C.TrustedProxies); err != nil {
+		panic(err)
+	}
 	group := r.Group("/api/v1")
+	group.Use(Auth(), RateLimit())
 	group.POST("/lyrics", lyrics)
 	group.POST("/lyrics/confirm", confirm)
 	group.POST("/lyrics/offset", offset)
+	group.POST("/lyrics/publish", publish)
+	group.GET("/lyrics/:sid/at", lyricsAt)
+	group.GET("/lyrics/:sid/lines", lyricsLines)
+	group.GET("/sync", syncSocket)
+	group.POST("/nowplaying", nowPlaying)
+	group.GET("/nowplaying", currentPlaying)
+	group.GET("/nowplaying/events", nowPlayingEvents)
+	group.GET("/settings", settings)
+	group.POST("/settings", saveSettings)
+
+	admin := group.Group("/admin")
+	admin.Use(AdminOnly())
+	admin.GET("/users", users)
+	admin.POST("/users", createUser)
+	admin.GET("/users/:id/tokens", userTokens)
+	admin.POST("/tokens", createToken)
+	admin.DELETE("/tokens/:id", revokeToken)
+	admin.GET("/providers/health", providersHealth)
 
-	_ = r.Run("[::]:8331")
+	_ = r.Run(config.C.Listen)
 }
 
+// confirm 保存用户选择的歌词, 保存的是搜索时的原始内容而不是返回给客户端的清理 / 屏蔽 / 转换后的内容
 func confirm(c *gin.Context) {
-	provider.Persist.Upsert(apputils.FromGinPostJson[model.MusicRelation](c))
+	user := currentUser(c)
+	relation := apputils.FromGinPostJson[model.MusicRelation](c)
+	relation, ok := resolveConfirm(relation, provider.Persist.Lyrics(model.SearchRequest{Id: relation.Sid}, user.Id))
+	if !ok {
+		response.Ret(http.StatusConflict, "lyrics were transformed and the original is no longer available, search again or confirm raw lyrics", c)
+		return
+	}
+	provider.Persist.Upsert(relation, user.Id)
+	provider.Persist.ClearStatus(relation.Sid)
 	response.Success(c)
 }
 
 func offset(c *gin.Context) {
-	provider.Persist.Offset(apputils.FromGinPostJson[model.MusicRelationOffset](c))
+	provider.Persist.Offset(apputils.FromGinPostJson[model.MusicRelationOffset](c), currentUser(c).Id)
 	response.Success(c)
 }
 
-var search = []provider.Provider{
+// publish 把用户确认或上传的逐行歌词发布到 LRCLIB 兼容服务, 需要在配置中开启
+func publish(c *gin.Context) {
+	if !config.C.LRCLIB.Publish {
+		response.Ret(http.StatusForbidden, "publishing is disabled", c)
+		return
+	}
+	// 只发布用户确认或上传的歌词, 匿名请求不允许
+	user := currentUser(c)
+	if user.Id == 0 && !user.Admin {
+		response.Ret(http.StatusUnauthorized, "user token is required", c)
+		return
+	}
+	request := apputils.FromGinPostJson[model.PublishRequest](c)
+	if request.Lyrics == "" {
+		// 只使用调用者自己确认的歌词, 不使用自动保存的共享默认选择
+		confirmed := provider.Persist.Confirmed(request.Sid, user.Id)
+		if len(confirmed) < 1 {
+			response.Ret(http.StatusNotFound, "lyrics not found, confirm or upload lyrics first", c)
+			return
+		}
+		if confirmed[0].Estimated {
+			response.Ret(http.StatusUnprocessableEntity, "estimated timing can not be published", c)
+			return
+		}
+		request.Lyrics, request.Offset = confirmed[0].Lyrics, confirmed[0].Offset
+	}
+	if err := (provider.LRCLIB{BaseURL: config.C.LRCLIB.PublishURL}).Publish(c.Request.Context(), request); err != nil {
+		response.Failed(err.Error(), c)
+		return
+	}
+	response.Success(c)
+}
+
+// 编译进来的 Provider, 启动时再加上配置中声明的 Provider 和插件, 统一做健康统计和熔断
+var search = provider.Guard(append(append([]provider.Provider{
 	provider.QQMusicLyrics{},
 	provider.NetEaseMusic{},
-	provider.LRCLIB{},
+	provider.LRCLIB{BaseURL: config.C.LRCLIB.BaseURL},
+	provider.KugouMusic{},
 	provider.KugouLK{},
 	provider.NetEaseLK{},
 	provider.QQMusicLK{},
-}
+}, provider.Declaratives(config.C.Providers.Declarative)...), provider.Plugins(config.C.Providers.Plugins)...), config.C.Providers.Breaker)
 
 func lyrics(c *gin.Context) {
 	request := apputils.FromGinPostJson[model.SearchRequest](c)
+	if !apputils.ValidScript(request.Script) {
+		response.Ret(http.StatusBadRequest, "script must be one of s, t, tw, hk, original", c)
+		return
+	}
+	if !roman.Valid(request.Roma) {
+		response.Ret(http.StatusBadRequest, romaUsage, c)
+		return
+	}
+	user := currentUser(c)
 	var data []model.MusicRelation
 	if request.Refresh != true {
-		data = provider.Persist.Lyrics(request)
+		data = provider.Persist.Lyrics(request, user.Id)
+		if len(data) < 1 && request.Id != "" {
+			if status, ok := provider.Persist.Status(request.Id); ok {
+				noLyrics(status, c)
+				return
+			}
+		}
+		data = provider.Languages(data, request.Languages)
 	}
 	if len(data) < 1 {
-		cd := make(chan []model.MusicRelation, 1)
-		var wg sync.WaitGroup
-		for _, p := range search {
-			wg.Add(1)
-			go func(p provider.Provider) {
-				defer wg.Done()
-				cd <- p.Lyrics(request)
-			}(p)
+		if !allowSearch(c) {
+			return
 		}
+		var status *model.LyricsStatus
+		data, status = searchLyrics(request, user)
+		if status != nil {
+			noLyrics(*status, c)
+			return
+		}
+	}
+	candidates.store(data)
+	response.Ok(view{script: request.Script, roma: request.Roma, raw: request.Raw, mask: masking(c, request.Mask)}.relations(data), c)
+}
 
-		go func() {
-			wg.Wait()
-			close(cd)
-		}()
+// searchLyrics 并发请求所有 Provider, 并把第一条结果作为默认选择保存;
+// 去掉占位内容后没有歌词时保存并返回纯音乐 / 没有找到的状态; 所有 Provider 都失败时不保存,
+// 成功的 Provider 少于 min_providers 时不保存没有找到
+func searchLyrics(request model.SearchRequest, user model.User) ([]model.MusicRelation, *model.LyricsStatus) {
+	type answer struct {
+		data []model.MusicRelation
+		err  error
+	}
+	var data []model.MusicRelation
+	cd := make(chan answer, 1)
+	var wg sync.WaitGroup
+	for _, p := range search {
+		wg.Add(1)
+		go func(p *provider.Guarded) {
+			defer wg.Done()
+			// 失败和熔断已经在 Guarded 中记录, 这里只收集结果
+			d, err := p.Lyrics(request)
+			cd <- answer{d, err}
+		}(p)
+	}
 
-		for d := range cd {
-			data = append(data, d...)
+	go func() {
+		wg.Wait()
+		close(cd)
+	}()
+
+	answered := 0
+	instrumental := false
+	for a := range cd {
+		if a.err == nil {
+			answered++
+		}
+		for _, relation := range a.data {
+			if status, ok := provider.Placeholder(relation); ok {
+				instrumental = instrumental || status == model.StatusInstrumental
+				continue
+			}
+			data = append(data, relation)
+		}
+	}
+	if len(data) < 1 {
+		if request.Id == "" || answered == 0 {
+			return nil, nil
 		}
-		if len(data) > 0 {
-			// 随机持久化一条, 后续用户点击后再更新
-			provider.Persist.Upsert(data[0])
+		status := model.StatusNotFound
+		retry := config.C.NoLyrics.NotFoundRetry
+		if instrumental {
+			status, retry = model.StatusInstrumental, config.C.NoLyrics.InstrumentalRetry
+		} else if answered < min(config.C.NoLyrics.MinProviders, len(search)) {
+			log.Printf("[INFO] No Lyrics [%s - %s]: only %d providers answered, not saved", request.Name, request.Singer, answered)
+			return nil, nil
 		}
+		log.Printf("[INFO] No Lyrics [%s - %s]: %s", request.Name, request.Singer, status)
+		result := provider.Persist.SetStatus(request.Id, status, retry)
+		return nil, &result
+	}
+	data = provider.Languages(provider.Dedup(provider.PseudoSync(provider.Consensus(data), request.Duration)), request.Languages)
+	if len(data) > 0 {
+		// 随机持久化一条, 后续用户点击后再更新; 登录用户不覆盖别人已确认的默认选择
+		if user.Id > 0 {
+			provider.Persist.Init(data[0])
+		} else {
+			provider.Persist.Upsert(data[0], 0)
+		}
+	}
+	return data, nil
+}
+
+// noLyrics 纯音乐 / 没有歌词时返回 404 和保存的状态, 与空列表区分
+func noLyrics(status model.LyricsStatus, c *gin.Context) {
+	response.Error(http.StatusNotFound, status.Status, status, c)
+}
+
+func providersHealth(c *gin.Context) {
+	var result []model.ProviderHealth
+	for _, p := range search {
+		result = append(result, p.Health())
 	}
-	response.Ok(data, c)
+	response.Ok(result, c)
 }
 
 func ErrorHolder() gin.HandlerFunc {
@@ -0,0 +1,50 @@
+package route
+
+import (
+	"encoding/base64"
+	"log"
+	apputils "lyrics/app-utils"
+	"lyrics/lyric"
+	"lyrics/model"
+	"lyrics/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryScript 读取 ?script=, 不支持的值返回 400
+func queryScript(c *gin.Context) (string, bool) {
+	script := c.Query("script")
+	if !apputils.ValidScript(script) {
+		response.Ret(http.StatusBadRequest, "script must be one of s, t, tw, hk, original", c)
+		return "", false
+	}
+	return script, true
+}
+
+// scriptRelation 转换歌名 / 歌手 / 歌词 / 翻译的文字, 保存的数据不受影响
+func scriptRelation(relation model.MusicRelation, script string) model.MusicRelation {
+	if script == "" || script == "original" {
+		return relation
+	}
+	convert := func(text string) string {
+		converted, err := apputils.ConvertScript(text, script)
+		if err != nil {
+			log.Printf("[ERROR] Failed Convert Script [%s]: %s", script, err)
+		}
+		return converted
+	}
+	relation.Name = convert(relation.Name)
+	relation.Singer = convert(relation.Singer)
+	relation.Lyrics = convertLayer(relation.Lyrics, convert)
+	relation.Trans = convertLayer(relation.Trans, convert)
+	return relation
+}
+
+func convertLayer(content string, convert func(string) string) string {
+	if content == "" {
+		return content
+	}
+	text := lyric.Decode(content)
+	return base64.StdEncoding.EncodeToString([]byte(lyric.Transform(text, convert)))
+}
@@ -0,0 +1,32 @@
+package route
+
+import (
+	apputils "lyrics/app-utils"
+	"lyrics/model"
+	"lyrics/provider"
+	"lyrics/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settings 当前用户的偏好设置, 需要用户 token
+func settings(c *gin.Context) {
+	user := currentUser(c)
+	if user.Id == 0 {
+		response.Ret(http.StatusUnauthorized, "user token is required", c)
+		return
+	}
+	response.Ok(model.UserSettings{MaskProfanity: user.MaskProfanity}, c)
+}
+
+func saveSettings(c *gin.Context) {
+	user := currentUser(c)
+	if user.Id == 0 {
+		response.Ret(http.StatusUnauthorized, "user token is required", c)
+		return
+	}
+	request := apputils.FromGinPostJson[model.UserSettings](c)
+	provider.Persist.SaveSettings(user.Id, request)
+	response.Ok(request, c)
+}
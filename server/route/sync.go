@@ -0,0 +1,86 @@
+package route
+
+import (
+	"log"
+	"lyrics/lyric"
+	"lyrics/model"
+	"lyrics/playback"
+	"lyrics/provider"
+	"lyrics/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// at 接口最多返回的上下文行数
+const maxContext = 20
+
+// 展示端 (ESP32 / OBS / 终端) 一般不带 Origin, 权限由 token 控制
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// syncSocket 客户端订阅 sid 并上报播放进度, 服务端推送当前行 / 当前字
+func syncSocket(c *gin.Context) {
+	user := currentUser(c)
+	v, ok := queryView(c)
+	if !ok {
+		return
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed Upgrade WebSocket %s", err)
+		return
+	}
+	playback.ServeSocket(conn, func(sid string) (model.MusicRelation, bool) {
+		return storedLyrics(sid, user.Id)
+	}, func(room string) (*playback.Room, error) {
+		return hub.Room(roomOwner(user), roomName(room))
+	}, v.relation)
+}
+
+// storedLyrics 读取已保存的歌词 (用户选择优先), 不会触发上游搜索
+func storedLyrics(sid string, userId int64) (model.MusicRelation, bool) {
+	data := provider.Persist.Lyrics(model.SearchRequest{Id: sid}, userId)
+	if len(data) < 1 {
+		return model.MusicRelation{}, false
+	}
+	return data[0], true
+}
+
+// lyricsAt GET /lyrics/:sid/at?ms=123456&context=2, ms 为播放器进度, 会先加上保存的 offset
+func lyricsAt(c *gin.Context) {
+	ms, err := strconv.ParseInt(c.Query("ms"), 10, 64)
+	if err != nil {
+		response.Ret(http.StatusBadRequest, "ms is required", c)
+		return
+	}
+	context, err := strconv.Atoi(c.DefaultQuery("context", "2"))
+	if err != nil || context < 0 {
+		response.Ret(http.StatusBadRequest, "invalid context", c)
+		return
+	}
+	context = min(context, maxContext)
+	v, ok := queryView(c)
+	if !ok {
+		return
+	}
+
+	relation, ok := storedLyrics(c.Param("sid"), currentUser(c).Id)
+	if !ok {
+		response.Ret(http.StatusNotFound, "lyrics not found, search it first", c)
+		return
+	}
+	relation = v.relation(relation)
+	snapshot := lyric.Parse(lyric.Decode(relation.Lyrics)).At(ms+relation.Offset, context)
+	response.Ok(gin.H{
+		"sid":      relation.Sid,
+		"lid":      relation.Lid,
+		"name":     relation.Name,
+		"singer":   relation.Singer,
+		"offset":   relation.Offset,
+		"snapshot": snapshot,
+	}, c)
+}
@@ -0,0 +1,54 @@
+package route
+
+import (
+	"lyrics/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// view 返回给客户端前对歌词的处理: 清理, 屏蔽脏话, 生成罗马音, 转换字形; 保存的数据不受影响
+type view struct {
+	script string
+	roma   string
+	// 跳过清理
+	raw  bool
+	mask bool
+}
+
+// queryView 读取 ?script= ?roma= ?raw= ?mask=, 不支持的值返回 400
+func queryView(c *gin.Context) (view, bool) {
+	script, ok := queryScript(c)
+	if !ok {
+		return view{}, false
+	}
+	roma, ok := queryRoma(c)
+	if !ok {
+		return view{}, false
+	}
+	mask, ok := queryMask(c)
+	if !ok {
+		return view{}, false
+	}
+	return view{script: script, roma: roma, raw: c.Query("raw") == "true", mask: masking(c, mask)}, true
+}
+
+func (v view) relation(relation model.MusicRelation) model.MusicRelation {
+	original := relation
+	if !v.raw {
+		relation = cleanRelation(relation)
+	}
+	if v.mask {
+		relation = maskRelation(relation)
+	}
+	relation = scriptRelation(romaRelation(relation, v.roma), v.script)
+	relation.Transformed = relation.Lyrics != original.Lyrics || relation.Trans != original.Trans || relation.Roma != original.Roma
+	return relation
+}
+
+func (v view) relations(data []model.MusicRelation) []model.MusicRelation {
+	result := make([]model.MusicRelation, len(data))
+	for i, relation := range data {
+		result[i] = v.relation(relation)
+	}
+	return result
+}